@@ -12,8 +12,8 @@ func init() {
 	rootCmd.AddCommand(&cobra.Command{
 		Use:     "git-branch",
 		Aliases: []string{"gb"},
-		Short:   "Edit git branch names",
-		Long:    "Interactive TUI for renaming git branches locally and on remote",
+		Short:   "Manage git branches",
+		Long:    "Interactive TUI for checking out, renaming, creating, deleting, pushing and fast-forwarding git branches",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			p := tea.NewProgram(messages.Standalone(gitbranch.New()))
 			_, err := p.Run()
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	tea "charm.land/bubbletea/v2"
 	"github.com/spf13/cobra"
 
@@ -9,15 +11,31 @@ import (
 )
 
 func init() {
-	rootCmd.AddCommand(&cobra.Command{
+	opts := testchanged.DefaultOptions()
+
+	cmd := &cobra.Command{
 		Use:     "test-changed",
 		Aliases: []string{"tc"},
 		Short:   "Run tests for files changed vs merge base",
 		Long:    "Detect changed files compared to the merge-base with the default branch and run affected tests",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(messages.Standalone(testchanged.New()))
+			if opts.FlakeRuns < 1 {
+				return fmt.Errorf("--flake-runs must be at least 1, got %d", opts.FlakeRuns)
+			}
+			p := tea.NewProgram(messages.Standalone(testchanged.NewWithOptions(opts)))
 			_, err := p.Run()
 			return err
 		},
-	})
+	}
+
+	cmd.Flags().BoolVar(&opts.Snapshot, "snapshot", opts.Snapshot, "run tests in a snapshot of the working tree so edits during a run are ignored")
+	cmd.Flags().IntVar(&opts.BenchCount, "bench-count", opts.BenchCount, "samples per benchmark when comparing against the merge base")
+	cmd.Flags().DurationVar(&opts.FuzzTime, "fuzz-time", opts.FuzzTime, "how long to fuzz each fuzz target")
+	cmd.Flags().IntVar(&opts.FlakeRuns, "flake-runs", opts.FlakeRuns, "how many times a flake hunt runs the chosen test")
+	cmd.Flags().BoolVar(&opts.Ordered, "ordered", opts.Ordered, "run targets one at a time, previously failed first, then slowest first")
+	cmd.Flags().BoolVar(&opts.FailFast, "fail-fast", opts.FailFast, "stop running targets after the first failing one")
+	cmd.Flags().DurationVar(&opts.NotifyAfter, "notify-after", opts.NotifyAfter, "notify when a run takes at least this long (0 disables)")
+	cmd.Flags().BoolVar(&opts.NotifySend, "notify-send", opts.NotifySend, "also send desktop notifications with notify-send")
+
+	rootCmd.AddCommand(cmd)
 }
@@ -0,0 +1,29 @@
+// Package gittest holds helpers for tests that drive a real git repository.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// Git returns a function that runs git in the working directory with a fixed
+// identity, failing the test if it exits non-zero.
+func Git(t testing.TB) func(args ...string) {
+	return func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+// WriteFile writes content to name, failing the test on error.
+func WriteFile(t testing.TB, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -41,6 +41,15 @@ var (
 	Remote = lipgloss.NewStyle().
 		Foreground(Gray)
 
+	Match = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).
+		Background(Cyan)
+
+	CurrentMatch = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#000000")).
+			Background(Pink).
+			Bold(true)
+
 	Box = lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(Cyan).
@@ -0,0 +1,139 @@
+package gitbranch
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// switchMode is how a checkout treats local changes.
+type switchMode int
+
+const (
+	switchPlain switchMode = iota // refuse if changes are in the way
+	switchStash                   // stash, switch, pop
+	switchMerge                   // git switch --merge
+)
+
+// dirtyChoices are the options offered when local changes block a
+// checkout, indexed by confirmIdx.
+var dirtyChoices = []struct {
+	label string
+	mode  switchMode
+}{
+	{"Stash, switch & pop", switchStash},
+	{"Carry over (--merge)", switchMerge},
+	{"Cancel", switchPlain},
+}
+
+var dirtyKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←→/hl", "select")),
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+	key.NewBinding(key.WithKeys("s", "m"), key.WithHelp("s/m", "stash/merge")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}}
+
+// switchArgs returns the git switch arguments that check out b.
+// Remote-tracking branches switch to their local counterpart, or create
+// one tracking them.
+func switchArgs(b Branch) []string {
+	switch {
+	case b.Remote == "":
+		return []string{b.Name}
+	case b.Local != "":
+		return []string{b.Local}
+	default:
+		return []string{"--create", localName(b), "--track", b.Name}
+	}
+}
+
+// cmdSwitch checks out b, handling local changes according to mode.
+func cmdSwitch(b Branch, mode switchMode) tea.Cmd {
+	args := switchArgs(b)
+	return func() tea.Msg {
+		msg := checkoutResultMsg{undo: checkoutUndo()}
+		switch mode {
+		case switchStash:
+			msg.conflicts, msg.err = stashSwitch(args)
+			msg.stashed = len(msg.conflicts) > 0
+		case switchMerge:
+			msg.conflicts, msg.err = mergeSwitch(args)
+		default:
+			msg.err = gitSwitch(args...)
+		}
+		return msg
+	}
+}
+
+// checkoutUndo records HEAD before a checkout. Without a HEAD to go back
+// to there is nothing to undo.
+func checkoutUndo() *undoOp {
+	head, detached, err := currentHead()
+	if err != nil {
+		return nil
+	}
+	return &undoOp{kind: undoCheckout, prevHead: head, detached: detached}
+}
+
+func (m Model) handleDirtyKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	choose := func(mode switchMode) (tea.Model, tea.Cmd) {
+		if mode == switchPlain {
+			m.state = stateBrowse
+			return m, nil
+		}
+		return startAsync(m, stateProcessing, "Switching branch...", cmdSwitch(m.editing, mode))
+	}
+	switch msg.String() {
+	case "esc", "q", "c":
+		return choose(switchPlain)
+	case "s":
+		return choose(switchStash)
+	case "m":
+		return choose(switchMerge)
+	case "left", "h", "shift+tab":
+		m.confirmIdx = max(0, m.confirmIdx-1)
+	case "right", "l", "tab":
+		m.confirmIdx = min(len(dirtyChoices)-1, m.confirmIdx+1)
+	case "enter", "space":
+		return choose(dirtyChoices[m.confirmIdx].mode)
+	}
+	return m, nil
+}
+
+func (m Model) dirtyView() string {
+	content := styles.Title.Render("Local Changes") + "\n\n"
+	content += "Switching to " + styles.Selected.Render(localName(m.editing)) +
+		" would overwrite local changes to:\n\n"
+	for _, f := range m.dirtyFiles {
+		content += "  " + styles.Err.Render(f) + "\n"
+	}
+	content += "\n"
+
+	labels := make([]string, len(dirtyChoices))
+	for i, c := range dirtyChoices {
+		labels[i] = c.label
+	}
+	content += choiceButtons(labels, m.confirmIdx) + "\n"
+	return content + "\n" + m.help.View(dirtyKeys)
+}
+
+// conflictSummary reports a switch whose carried-over changes conflicted.
+func conflictSummary(b Branch, conflicts []string, stashed bool) string {
+	lines := []string{
+		styles.Success.Render("✓") + " Switched to " + styles.Selected.Render(localName(b)),
+		styles.Err.Render("✗") + " Your local changes conflicted in:",
+	}
+	for _, f := range conflicts {
+		lines = append(lines, "    "+styles.Err.Render(f))
+	}
+	lines = append(lines, "")
+	if stashed {
+		lines = append(lines, styles.Dimmed.Render("Your changes are still in the stash. Resolve the conflicts, then run git stash drop."))
+	} else {
+		lines = append(lines, styles.Dimmed.Render("Resolve the conflict markers in these files."))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,171 @@
+package gitbranch
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestOverwrittenFiles(t *testing.T) {
+	stderr := "error: Your local changes to the following files would be overwritten by checkout:\n" +
+		"\ta.txt\n\tdir/b file.go\n" +
+		"Please commit your changes or stash them before you switch branches.\nAborting"
+	if got := overwrittenFiles(stderr); !reflect.DeepEqual(got, []string{"a.txt", "dir/b file.go"}) {
+		t.Errorf("overwrittenFiles = %q", got)
+	}
+	if got := overwrittenFiles("fatal: invalid reference: nope"); got != nil {
+		t.Errorf("unrelated error parsed as %q", got)
+	}
+}
+
+// dirtyRepo creates a gitRepo where "other" changes the first line of f,
+// then edits the given line of f in the working tree on main.
+func dirtyRepo(t *testing.T, line int) {
+	t.Helper()
+	gitRepo(t)
+	git := gittest.Git(t)
+	write := func(lines ...string) {
+		t.Helper()
+		gittest.WriteFile(t, "f", strings.Join(lines, "\n")+"\n")
+	}
+	write("1", "2", "3", "4", "5")
+	git("add", "f")
+	git("commit", "-qm", "f")
+	git("switch", "-qc", "other")
+	write("one", "2", "3", "4", "5")
+	git("commit", "-qam", "other")
+	git("switch", "-q", "main")
+
+	lines := []string{"1", "2", "3", "4", "5"}
+	lines[line-1] = "local"
+	write(lines...)
+}
+
+func stashCount(t *testing.T) int {
+	t.Helper()
+	out, err := exec.Command("git", "stash", "list").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(out), "\n")
+}
+
+func TestCmdSwitch_DirtyTree(t *testing.T) {
+	dirtyRepo(t, 5)
+	msg := cmdSwitch(Branch{Name: "other"}, switchPlain)().(checkoutResultMsg)
+	var dirty *dirtyTreeError
+	if !errors.As(msg.err, &dirty) || !reflect.DeepEqual(dirty.files, []string{"f"}) {
+		t.Fatalf("err = %v, want a dirty tree error for f", msg.err)
+	}
+
+	msg = cmdSwitch(Branch{Name: "other"}, switchStash)().(checkoutResultMsg)
+	if msg.err != nil || len(msg.conflicts) > 0 {
+		t.Fatalf("stash switch = %+v", msg)
+	}
+	if head, _, _ := currentHead(); head != "other" {
+		t.Errorf("HEAD = %q, want other", head)
+	}
+	data, _ := os.ReadFile("f")
+	if string(data) != "one\n2\n3\n4\nlocal\n" {
+		t.Errorf("f = %q, want both changes", data)
+	}
+	if n := stashCount(t); n != 0 {
+		t.Errorf("stash should be popped, %d entries left", n)
+	}
+}
+
+func TestCmdSwitch_StashConflictKeepsStash(t *testing.T) {
+	dirtyRepo(t, 1)
+	msg := cmdSwitch(Branch{Name: "other"}, switchStash)().(checkoutResultMsg)
+	if msg.err != nil || !reflect.DeepEqual(msg.conflicts, []string{"f"}) || !msg.stashed {
+		t.Fatalf("stash switch = %+v, want a conflict in f", msg)
+	}
+	if n := stashCount(t); n != 1 {
+		t.Errorf("stash should be kept, %d entries", n)
+	}
+}
+
+func TestStashSwitch_LeavesOlderStash(t *testing.T) {
+	dirtyRepo(t, 5)
+	gittest.Git(t)("stash", "push", "-q")
+
+	if conflicts, err := stashSwitch([]string{"other"}); err != nil || len(conflicts) > 0 {
+		t.Fatalf("stash switch with a clean tree = %v, %v", conflicts, err)
+	}
+	if n := stashCount(t); n != 1 {
+		t.Errorf("the older stash should be left alone, %d entries", n)
+	}
+	if data, _ := os.ReadFile("f"); string(data) != "one\n2\n3\n4\n5\n" {
+		t.Errorf("f = %q, want other's content only", data)
+	}
+}
+
+func TestStashSwitch_FailedSwitchRestoresChanges(t *testing.T) {
+	dirtyRepo(t, 5)
+	if _, err := stashSwitch([]string{"missing"}); err == nil {
+		t.Fatal("expected the switch to fail")
+	}
+	if n := stashCount(t); n != 0 {
+		t.Errorf("stash should be popped back, %d entries", n)
+	}
+	if data, _ := os.ReadFile("f"); string(data) != "1\n2\n3\n4\nlocal\n" {
+		t.Errorf("f = %q, want the local change back", data)
+	}
+}
+
+func TestCmdSwitch_Merge(t *testing.T) {
+	dirtyRepo(t, 1)
+	msg := cmdSwitch(Branch{Name: "other"}, switchMerge)().(checkoutResultMsg)
+	if msg.err != nil || !reflect.DeepEqual(msg.conflicts, []string{"f"}) || msg.stashed {
+		t.Fatalf("merge switch = %+v, want a conflict in f", msg)
+	}
+	if head, _, _ := currentHead(); head != "other" {
+		t.Errorf("HEAD = %q, want other", head)
+	}
+}
+
+func TestDirtyCheckout_Choices(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.editing = testBranches[1]
+
+	r, _ := m.Update(checkoutResultMsg{err: &dirtyTreeError{files: []string{"a.go"}}})
+	m = r.(Model)
+	if m.state != stateDirtyCheckout || m.errSplash != "" {
+		t.Fatalf("expected the dirty checkout screen, got state %d", m.state)
+	}
+	if !strings.Contains(m.dirtyView(), "a.go") {
+		t.Error("dirty view should list the conflicting files")
+	}
+
+	r, cmd := m.Update(keyRune('m'))
+	if r.(Model).state != stateProcessing || cmd == nil {
+		t.Error("m should switch carrying changes over")
+	}
+
+	m = pressAll(m, keyCode(tea.KeyRight), keyCode(tea.KeyRight))
+	r, cmd = m.Update(keyCode(tea.KeyEnter))
+	if r.(Model).state != stateBrowse || cmd != nil {
+		t.Error("selecting cancel should return to browse")
+	}
+}
+
+func TestCheckoutResult_ConflictsShowResult(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.editing = testBranches[1]
+
+	r, _ := m.Update(checkoutResultMsg{conflicts: []string{"a.go"}, stashed: true})
+	m = r.(Model)
+	if m.state != stateResult {
+		t.Fatalf("expected result, got state %d", m.state)
+	}
+	if !strings.Contains(m.result, "a.go") || !strings.Contains(m.result, "still in the stash") {
+		t.Errorf("result = %q", m.result)
+	}
+}
@@ -0,0 +1,350 @@
+package gitbranch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// inactiveSteps are the choices for how long a branch may go without a
+// commit before cleanup suggests it.
+var inactiveSteps = []int{14, 30, 60, 90, 180, 365}
+
+// defaultInactiveStep is the index of the default in inactiveSteps.
+const defaultInactiveStep = 3
+
+var cleanupKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("space"), key.WithHelp("space", "toggle")),
+	key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "all/none")),
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←→", "inactive days")),
+	key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "remote too")),
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "delete")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}}
+
+// cleanupItem is a branch the cleanup screen suggests deleting.
+type cleanupItem struct {
+	branch  Branch
+	reasons []string
+	checked bool
+}
+
+type mergedLoadedMsg struct {
+	merged map[string]bool
+	err    error
+}
+
+// cleanupResult is the outcome of deleting one branch in a cleanup batch.
+// remoteErr is only meaningful when remote is set.
+type cleanupResult struct {
+	name      string
+	err       error
+	remote    string
+	remoteErr error
+}
+
+// unmergedBranch is a branch git refused to delete in a cleanup batch, with
+// the commits a forced delete would lose.
+type unmergedBranch struct {
+	branch  Branch
+	commits []string
+}
+
+type cleanupDoneMsg struct {
+	results  []cleanupResult
+	unmerged []unmergedBranch // refused safe deletes, to confirm forcing
+	undo     *undoOp          // restores the deleted local branches
+}
+
+// fetchMerged loads the local branches fully merged into base.
+func fetchMerged(base string) tea.Cmd {
+	return func() tea.Msg {
+		merged, err := mergedBranches(base)
+		return mergedLoadedMsg{merged: merged, err: err}
+	}
+}
+
+// isDefaultBranch reports whether b is the default branch or the local
+// branch that follows it. Cleanup never suggests it.
+func isDefaultBranch(b Branch, base string) bool {
+	return b.Name == base || b.Upstream == base || strings.HasSuffix(base, "/"+b.Name)
+}
+
+// staleBranches returns the local branches worth deleting: merged into the
+// default branch, with a deleted upstream, or without a commit in days. The
+// current and default branches are never included.
+func staleBranches(branches []Branch, merged map[string]bool, base string, days int, now time.Time) []cleanupItem {
+	cutoff := now.AddDate(0, 0, -days)
+	var items []cleanupItem
+	for _, b := range branches {
+		if b.IsCurrent || isDefaultBranch(b, base) {
+			continue
+		}
+		var reasons []string
+		if merged[b.Name] {
+			reasons = append(reasons, "merged")
+		}
+		if b.UpstreamGone {
+			reasons = append(reasons, "upstream gone")
+		}
+		if !b.CommitDate.IsZero() && b.CommitDate.Before(cutoff) {
+			reasons = append(reasons, "inactive "+relativeTime(b.CommitDate, now))
+		}
+		if len(reasons) > 0 {
+			items = append(items, cleanupItem{branch: b, reasons: reasons, checked: true})
+		}
+	}
+	return items
+}
+
+// refreshCleanup recomputes the suggestions, keeping the choices made for
+// branches that are still suggested.
+func (m *Model) refreshCleanup() {
+	unchecked := make(map[string]bool)
+	for _, it := range m.cleanup {
+		if !it.checked {
+			unchecked[it.branch.Name] = true
+		}
+	}
+	m.cleanup = staleBranches(m.branches, m.merged, m.base, inactiveSteps[m.inactiveStep], time.Now())
+	for i := range m.cleanup {
+		m.cleanup[i].checked = !unchecked[m.cleanup[i].branch.Name]
+	}
+	m.cleanupCursor = max(0, min(m.cleanupCursor, len(m.cleanup)-1))
+	ensureCursorVisible(&m.cleanupViewport, m.cleanupCursor)
+}
+
+func (m Model) cleanupChecked() []Branch {
+	var branches []Branch
+	for _, it := range m.cleanup {
+		if it.checked {
+			branches = append(branches, it.branch)
+		}
+	}
+	return branches
+}
+
+// cmdCleanup deletes branches, and their upstreams when remote is set and
+// the upstream still exists. Every branch is attempted. Without force,
+// branches git considers unmerged are left alone and returned with their
+// commits so the user can confirm forcing them.
+func (m Model) cmdCleanup(branches []Branch, remote, force bool) tea.Cmd {
+	base := m.base
+	if base == "" {
+		base = "HEAD"
+	}
+	return func() tea.Msg {
+		var msg cleanupDoneMsg
+		undo := undoOp{kind: undoDelete}
+		for _, b := range branches {
+			sha, err := branchSHA(b.Name)
+			if err == nil {
+				err = deleteBranch(b.Name, force)
+			}
+			if errors.Is(err, errNotMerged) {
+				var commits []string
+				if commits, err = unmergedCommits(base, b.Name); err == nil {
+					msg.unmerged = append(msg.unmerged, unmergedBranch{branch: b, commits: commits})
+					continue
+				}
+			}
+			r := cleanupResult{name: b.Name, err: err}
+			if err == nil {
+				undo.deleted = append(undo.deleted, deletedBranch{name: b.Name, sha: sha, upstream: b.Upstream})
+			}
+			if r.err == nil && remote && b.HasRemote && !b.UpstreamGone {
+				r.remote = b.Upstream
+				r.remoteErr = deleteRemoteBranch(b.UpstreamRemote, b.UpstreamBranch)
+			}
+			msg.results = append(msg.results, r)
+		}
+		if len(undo.deleted) > 0 {
+			msg.undo = &undo
+		}
+		return msg
+	}
+}
+
+// finishCleanup shows the outcome of a cleanup batch, counting branches
+// still awaiting a forced delete as kept.
+func (m Model) finishCleanup() Model {
+	results := m.cleanupResults
+	for _, u := range m.cleanupUnmerged {
+		results = append(results, cleanupResult{name: u.branch.Name, err: errNotMerged})
+	}
+	m.cleanupResults = nil
+	m.cleanupUnmerged = nil
+	m.state = stateResult
+	m.result = cleanupSummary(results)
+	return m
+}
+
+// handleForceCleanupKey handles the confirm screen for the unmerged
+// branches of a cleanup batch.
+func (m Model) handleForceCleanupKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "n", "q":
+		return m.finishCleanup(), nil
+	case "D":
+		branches := make([]Branch, len(m.cleanupUnmerged))
+		for i, u := range m.cleanupUnmerged {
+			branches[i] = u.branch
+		}
+		m.cleanupUnmerged = nil
+		label := fmt.Sprintf("Deleting %d branches...", len(branches))
+		return startAsync(m, stateProcessing, label, m.cmdCleanup(branches, m.cleanupRemote, true))
+	}
+	return m, nil
+}
+
+// maxUnmergedPerBranch bounds the commits listed for each branch before a
+// forced cleanup.
+const maxUnmergedPerBranch = 5
+
+func (m Model) forceCleanupView() string {
+	base := m.base
+	if base == "" {
+		base = "HEAD"
+	}
+	content := styles.Title.Render("Branches Not Merged") + "\n\n"
+	content += fmt.Sprintf("%d branches are not fully merged. Deleting them will lose commits not on %s:\n",
+		len(m.cleanupUnmerged), styles.Remote.Render(base))
+	for _, u := range m.cleanupUnmerged {
+		content += "\n" + styles.Selected.Render(u.branch.Name) + "\n"
+		if len(u.commits) == 0 {
+			content += styles.Dimmed.Render("  Its commits are all on "+base+", but not on its upstream or HEAD.") + "\n"
+			continue
+		}
+		for _, c := range u.commits[:min(len(u.commits), maxUnmergedPerBranch)] {
+			hash, subject, _ := strings.Cut(c, " ")
+			content += "  " + styles.Subtitle.Render(hash) + " " + subject + "\n"
+		}
+		if n := len(u.commits) - maxUnmergedPerBranch; n > 0 {
+			content += styles.Dimmed.Render(fmt.Sprintf("  … and %d more", n)) + "\n"
+		}
+	}
+	return content + "\n" + m.help.View(confirmForceKeys)
+}
+
+// cleanupSummary renders the per-branch outcome of a cleanup batch.
+func cleanupSummary(results []cleanupResult) string {
+	deleted := 0
+	var lines []string
+	for _, r := range results {
+		if r.err != nil {
+			lines = append(lines, styles.Err.Render("✗")+" "+r.name+": "+styles.Err.Render(r.err.Error()))
+			continue
+		}
+		deleted++
+		line := styles.Success.Render("✓") + " Deleted " + styles.Dimmed.Render(r.name)
+		switch {
+		case r.remote == "":
+		case r.remoteErr != nil:
+			line += "  " + styles.Err.Render("✗ "+r.remote+": "+r.remoteErr.Error())
+		default:
+			line += "  " + styles.Remote.Render("✓ "+r.remote)
+		}
+		lines = append(lines, line)
+	}
+	header := fmt.Sprintf("Deleted %d of %d branches", deleted, len(results))
+	return strings.Join(append([]string{header, ""}, lines...), "\n")
+}
+
+func (m Model) handleCleanupKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.state = stateBrowse
+	case "up", "k":
+		if m.cleanupCursor > 0 {
+			m.cleanupCursor--
+			ensureCursorVisible(&m.cleanupViewport, m.cleanupCursor)
+		}
+	case "down", "j":
+		if m.cleanupCursor < len(m.cleanup)-1 {
+			m.cleanupCursor++
+			ensureCursorVisible(&m.cleanupViewport, m.cleanupCursor)
+		}
+	case "space", "x":
+		if len(m.cleanup) > 0 {
+			m.cleanup = slices.Clone(m.cleanup)
+			m.cleanup[m.cleanupCursor].checked = !m.cleanup[m.cleanupCursor].checked
+		}
+	case "a":
+		all := len(m.cleanupChecked()) == len(m.cleanup)
+		m.cleanup = slices.Clone(m.cleanup)
+		for i := range m.cleanup {
+			m.cleanup[i].checked = !all
+		}
+	case "left", "h":
+		if m.inactiveStep > 0 {
+			m.inactiveStep--
+			m.refreshCleanup()
+		}
+	case "right", "l":
+		if m.inactiveStep < len(inactiveSteps)-1 {
+			m.inactiveStep++
+			m.refreshCleanup()
+		}
+	case "R":
+		m.cleanupRemote = !m.cleanupRemote
+	case "enter":
+		branches := m.cleanupChecked()
+		if len(branches) == 0 {
+			break
+		}
+		label := fmt.Sprintf("Deleting %d branches...", len(branches))
+		m.cleanupResults = nil
+		return startAsync(m, stateProcessing, label, m.cmdCleanup(branches, m.cleanupRemote, false))
+	}
+	return m, nil
+}
+
+func (m Model) cleanupView() string {
+	content := styles.Title.Render("Clean Up Branches") + "\n\n"
+
+	remote := "no"
+	if m.cleanupRemote {
+		remote = "yes"
+	}
+	content += styles.Dimmed.Render("Inactive after: ") + styles.Subtitle.Render(fmt.Sprintf("%dd", inactiveSteps[m.inactiveStep])) +
+		styles.Dimmed.Render("   Delete remote branches too: ") + styles.Subtitle.Render(remote) + "\n\n"
+
+	if len(m.cleanup) == 0 {
+		content += styles.Dimmed.Render("No stale branches.") + "\n"
+		return content + "\n" + m.help.View(cleanupKeys)
+	}
+
+	width := len("branch")
+	for _, it := range m.cleanup {
+		width = max(width, len(it.branch.Name))
+	}
+	width = min(width, maxNameWidth)
+
+	lines := make([]string, 0, len(m.cleanup))
+	for i, it := range m.cleanup {
+		cursor := "  "
+		nameStyle := styles.Dimmed
+		box := "[ ] "
+		if it.checked {
+			box = "[x] "
+			nameStyle = styles.Err
+		}
+		if i == m.cleanupCursor {
+			cursor = styles.Selected.Render("> ")
+			nameStyle = nameStyle.Bold(true)
+		}
+		lines = append(lines, cursor+box+nameStyle.Render(cell(it.branch.Name, width))+
+			"  "+styles.Dimmed.Render(strings.Join(it.reasons, ", ")))
+	}
+	m.cleanupViewport.SetContent(strings.Join(lines, "\n"))
+	content += m.cleanupViewport.View() + "\n"
+	content += styles.Dimmed.Render(fmt.Sprintf("%d of %d selected", len(m.cleanupChecked()), len(m.cleanup))) + "\n"
+	return content + "\n" + m.help.View(cleanupKeys)
+}
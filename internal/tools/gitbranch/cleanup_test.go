@@ -0,0 +1,204 @@
+package gitbranch
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var cleanupNow = time.Now()
+
+var cleanupBranches = []Branch{
+	{Name: "main", Upstream: "origin/main", HasRemote: true, CommitDate: cleanupNow.AddDate(-1, 0, 0)},
+	{Name: "current", IsCurrent: true, CommitDate: cleanupNow.AddDate(-1, 0, 0)},
+	{Name: "done", CommitDate: cleanupNow.AddDate(0, 0, -2)},
+	{Name: "gone", Upstream: "origin/gone", HasRemote: true, UpstreamGone: true, CommitDate: cleanupNow},
+	{Name: "old", CommitDate: cleanupNow.AddDate(0, 0, -100)},
+	{Name: "active", CommitDate: cleanupNow.AddDate(0, 0, -1)},
+}
+
+func itemNames(items []cleanupItem) []string {
+	var names []string
+	for _, it := range items {
+		names = append(names, it.branch.Name)
+	}
+	return names
+}
+
+func TestStaleBranches(t *testing.T) {
+	merged := map[string]bool{"main": true, "current": true, "done": true}
+	items := staleBranches(cleanupBranches, merged, "origin/main", 90, cleanupNow)
+
+	if got, want := itemNames(items), []string{"done", "gone", "old"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stale = %v, want %v", got, want)
+	}
+	wantReasons := [][]string{{"merged"}, {"upstream gone"}, {"inactive 3mo ago"}}
+	for i, it := range items {
+		if !reflect.DeepEqual(it.reasons, wantReasons[i]) {
+			t.Errorf("%s reasons = %v, want %v", it.branch.Name, it.reasons, wantReasons[i])
+		}
+		if !it.checked {
+			t.Errorf("%s should be pre-checked", it.branch.Name)
+		}
+	}
+
+	if got := itemNames(staleBranches(cleanupBranches, nil, "origin/main", 365, cleanupNow)); !reflect.DeepEqual(got, []string{"gone"}) {
+		t.Errorf("with a year threshold and nothing merged, stale = %v", got)
+	}
+}
+
+func TestCleanup_ToggleAndThresholdKeepChoices(t *testing.T) {
+	m := modelWithBranches(cleanupBranches)
+	m.base = "origin/main"
+	r, _ := m.Update(mergedLoadedMsg{merged: map[string]bool{"done": true}})
+	m = r.(Model)
+	if m.state != stateCleanup {
+		t.Fatalf("expected cleanup screen, got state %d", m.state)
+	}
+
+	// Uncheck "done", then widen the threshold so "old" is no longer stale.
+	m = pressAll(m, keyCode(tea.KeySpace), keyCode(tea.KeyRight))
+	if got := itemNames(m.cleanup); !reflect.DeepEqual(got, []string{"done", "gone"}) {
+		t.Fatalf("after widening, stale = %v", got)
+	}
+	if m.cleanup[0].checked {
+		t.Error("unchecking done should survive a threshold change")
+	}
+
+	m = pressAll(m, keyRune('a'))
+	if len(m.cleanupChecked()) != 2 {
+		t.Errorf("a should check everything, got %d checked", len(m.cleanupChecked()))
+	}
+	m = pressAll(m, keyRune('a'))
+	if len(m.cleanupChecked()) != 0 {
+		t.Errorf("a again should uncheck everything, got %d checked", len(m.cleanupChecked()))
+	}
+
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	if r.(Model).state != stateCleanup || cmd != nil {
+		t.Error("enter with nothing checked should do nothing")
+	}
+}
+
+func TestCleanup_EnterDeletesBatch(t *testing.T) {
+	m := modelWithBranches(cleanupBranches)
+	m.base = "origin/main"
+	r, _ := m.Update(mergedLoadedMsg{})
+	m = pressAll(r.(Model), keyRune('R'))
+	if !m.cleanupRemote {
+		t.Fatal("R should toggle remote deletion")
+	}
+
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateProcessing || cmd == nil {
+		t.Fatalf("expected batch delete to start, got state %d", m.state)
+	}
+
+	r, _ = m.Update(cleanupDoneMsg{results: []cleanupResult{
+		{name: "gone"},
+		{name: "old", err: errForTest("boom")},
+	}})
+	m = r.(Model)
+	if m.state != stateResult {
+		t.Fatalf("expected result, got state %d", m.state)
+	}
+	result := ansi.Strip(m.result)
+	for _, want := range []string{"Deleted 1 of 2 branches", "✓ Deleted gone", "✗ old: boom"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("result missing %q:\n%s", want, result)
+		}
+	}
+}
+
+func TestBrowse_CleanupNeedsDefaultBranch(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	r, _ := m.Update(keyRune('x'))
+	if r.(Model).errSplash == "" {
+		t.Error("expected an error without a default branch")
+	}
+}
+
+func TestCmdCleanup_DeletesBranches(t *testing.T) {
+	gitRepo(t)
+	if out, err := exec.Command("git", "branch", "done", "main~1").CombinedOutput(); err != nil {
+		t.Fatalf("git branch: %v\n%s", err, out)
+	}
+
+	merged, err := mergedBranches("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(merged, map[string]bool{"main": true, "done": true}) {
+		t.Errorf("merged = %v", merged)
+	}
+
+	msg := Model{}.cmdCleanup([]Branch{{Name: "done"}, {Name: "missing"}}, true, false)().(cleanupDoneMsg)
+	if len(msg.results) != 2 || msg.results[0].err != nil || msg.results[1].err == nil {
+		t.Errorf("unexpected results %+v", msg.results)
+	}
+	branches, _ := getBranches("")
+	for _, b := range branches {
+		if b.Name == "done" {
+			t.Error("done should have been deleted")
+		}
+	}
+}
+
+func TestCmdCleanup_ConfirmsUnmerged(t *testing.T) {
+	gitRepo(t)
+	if out, err := exec.Command("git", "branch", "done", "main~1").CombinedOutput(); err != nil {
+		t.Fatalf("git branch: %v\n%s", err, out)
+	}
+	m := modelWithBranches([]Branch{{Name: "main", IsCurrent: true}, {Name: "done"}, {Name: "topic"}})
+	m.base = "main"
+
+	msg := m.cmdCleanup([]Branch{{Name: "done"}, {Name: "topic"}}, false, false)().(cleanupDoneMsg)
+	if len(msg.results) != 1 || msg.results[0].name != "done" || msg.results[0].err != nil {
+		t.Fatalf("results = %+v", msg.results)
+	}
+	if len(msg.unmerged) != 1 || msg.unmerged[0].branch.Name != "topic" || len(msg.unmerged[0].commits) != 1 {
+		t.Fatalf("unmerged = %+v", msg.unmerged)
+	}
+	if _, err := branchSHA("topic"); err != nil {
+		t.Fatal("topic should not be deleted without confirming")
+	}
+
+	r, _ := m.Update(msg)
+	confirm := r.(Model)
+	if confirm.state != stateConfirmForce || !strings.Contains(ansi.Strip(confirm.forceCleanupView()), "topic work") {
+		t.Fatalf("state %d, view %q", confirm.state, confirm.forceCleanupView())
+	}
+
+	kept := pressAll(confirm, keyCode(tea.KeyEscape))
+	if kept.state != stateResult || !strings.Contains(kept.result, "Deleted 1 of 2") || kept.cleanupUnmerged != nil {
+		t.Errorf("esc: state %d, result %q", kept.state, kept.result)
+	}
+
+	r, cmd := confirm.Update(keyRune('D'))
+	if r.(Model).state != stateProcessing || cmd == nil {
+		t.Fatal("D should force the unmerged deletes")
+	}
+	forced := r.(Model).cmdCleanup([]Branch{{Name: "topic"}}, false, true)().(cleanupDoneMsg)
+	r, _ = r.(Model).Update(forced)
+	if got := r.(Model); got.state != stateResult || !strings.Contains(got.result, "Deleted 2 of 2") {
+		t.Errorf("forced: state %d, result %q", got.state, got.result)
+	}
+	if len(r.(Model).undo) != 2 {
+		t.Errorf("expected an undo for each batch, got %d", len(r.(Model).undo))
+	}
+}
+
+// pressAll sends keys to m in order.
+func pressAll(m Model, keys ...tea.KeyPressMsg) Model {
+	for _, k := range keys {
+		r, _ := m.Update(k)
+		m = r.(Model)
+	}
+	return m
+}
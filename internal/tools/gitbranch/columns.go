@@ -0,0 +1,181 @@
+package gitbranch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+const (
+	maxNameWidth    = 40
+	ageWidth        = 8
+	upstreamWidth   = 8
+	authorWidth     = 14
+	minSubjectWidth = 10
+)
+
+// columns holds the widths of the browse list columns. A zero width hides
+// the column.
+type columns struct {
+	name, age, base, upstream, author, subject int
+	baseLabel                                  string
+}
+
+// columns sizes the list to the branches and the viewport. The subject gets
+// whatever width is left over.
+func (m Model) columns() columns {
+	c := columns{age: ageWidth, author: authorWidth}
+	if !m.showRemote {
+		c.upstream = upstreamWidth
+	}
+	c.name = len("branch")
+	for _, b := range m.list() {
+		c.name = max(c.name, ansi.StringWidth(b.Name))
+	}
+	c.name = min(c.name, maxNameWidth)
+	if m.base != "" {
+		c.baseLabel = "vs " + m.base
+		c.base = len(c.baseLabel)
+	}
+
+	// Cursor and current-branch marker, then two spaces before each column.
+	used := 4 + c.name + 2 + c.age + 2 + c.author + 2
+	for _, w := range []int{c.base, c.upstream} {
+		if w > 0 {
+			used += 2 + w
+		}
+	}
+	if rest := m.viewport.Width() - used; rest >= minSubjectWidth {
+		c.subject = rest
+	}
+	return c
+}
+
+// cell truncates or pads s to exactly width cells.
+func cell(s string, width int) string {
+	s = ansi.Truncate(s, width, "…")
+	return s + strings.Repeat(" ", width-ansi.StringWidth(s))
+}
+
+func (c columns) header() string {
+	h := "    " + cell("branch", c.name) + "  " + cell("updated", c.age)
+	if c.base > 0 {
+		h += "  " + cell(c.baseLabel, c.base)
+	}
+	if c.upstream > 0 {
+		h += "  " + cell("upstream", c.upstream)
+	}
+	h += "  " + cell("author", c.author)
+	if c.subject > 0 {
+		h += "  subject"
+	}
+	return h
+}
+
+// row renders b's columns after the cursor and current-branch marker,
+// highlighting the filter matches at positions in the name. A non-empty
+// marker replaces the subject and is always shown.
+func (c columns) row(b Branch, positions []int, nameStyle lipgloss.Style, marker string, now time.Time) string {
+	r := nameCell(b.Name, positions, c.name, nameStyle) +
+		"  " + styles.Dimmed.Render(cell(relativeTime(b.CommitDate, now), c.age))
+	if c.base > 0 {
+		r += "  " + styles.Remote.Render(cell(divergence(b.BaseAhead, b.BaseBehind, "+", "-"), c.base))
+	}
+
+	if c.upstream > 0 {
+		switch {
+		case b.UpstreamGone:
+			r += "  " + styles.Err.Render(cell("gone", c.upstream))
+		case b.HasRemote:
+			r += "  " + styles.Remote.Render(cell(divergence(b.Ahead, b.Behind, "↑", "↓"), c.upstream))
+		default:
+			r += "  " + cell("", c.upstream)
+		}
+	}
+	r += "  " + styles.Dimmed.Render(cell(b.Author, c.author))
+
+	switch {
+	case marker != "":
+		r += "  " + styles.Err.Render(marker)
+	case c.subject > 0:
+		r += "  " + ansi.Truncate(b.Subject, c.subject, "…")
+	}
+	return strings.TrimRight(r, " ")
+}
+
+// nameCell renders name in width cells, highlighting the runes at
+// positions. Runs of runes are rendered together to keep escapes short.
+func nameCell(name string, positions []int, width int, style lipgloss.Style) string {
+	text := []rune(cell(name, width))
+	limit := len(text)
+	if ansi.StringWidth(name) > width {
+		limit = width - 1 // the rest was replaced by the ellipsis
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		if p < limit {
+			matched[p] = true
+		}
+	}
+
+	var b strings.Builder
+	for start := 0; start < len(text); {
+		end := start + 1
+		for end < len(text) && matched[end] == matched[start] {
+			end++
+		}
+		s := string(text[start:end])
+		if matched[start] {
+			b.WriteString(styles.Match.Render(s))
+		} else {
+			b.WriteString(style.Render(s))
+		}
+		start = end
+	}
+	return b.String()
+}
+
+// divergence formats ahead/behind counts, e.g. "+3 -12", or "=" when level.
+func divergence(ahead, behind int, aheadSign, behindSign string) string {
+	var parts []string
+	if ahead > 0 {
+		parts = append(parts, aheadSign+strconv.Itoa(ahead))
+	}
+	if behind > 0 {
+		parts = append(parts, behindSign+strconv.Itoa(behind))
+	}
+	if len(parts) == 0 {
+		return "="
+	}
+	return strings.Join(parts, " ")
+}
+
+// relativeTime formats how long before now t was, e.g. "3d ago".
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 60*24*time.Hour:
+		return fmt.Sprintf("%dw ago", int(d.Hours()/24/7))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+	}
+}
@@ -0,0 +1,89 @@
+package gitbranch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{4 * 24 * time.Hour, "4d ago"},
+		{21 * 24 * time.Hour, "3w ago"},
+		{95 * 24 * time.Hour, "3mo ago"},
+		{800 * 24 * time.Hour, "2y ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("relativeTime(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+	if got := relativeTime(time.Time{}, now); got != "" {
+		t.Errorf("zero time = %q, want empty", got)
+	}
+}
+
+func TestDivergence(t *testing.T) {
+	tests := []struct {
+		ahead, behind int
+		want          string
+	}{
+		{0, 0, "="},
+		{3, 0, "↑3"},
+		{0, 2, "↓2"},
+		{3, 12, "↑3 ↓12"},
+	}
+	for _, tt := range tests {
+		if got := divergence(tt.ahead, tt.behind, "↑", "↓"); got != tt.want {
+			t.Errorf("divergence(%d, %d) = %q, want %q", tt.ahead, tt.behind, got, tt.want)
+		}
+	}
+}
+
+func TestColumns_SubjectFillsWidth(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.base = "origin/main"
+	m.viewport.SetWidth(120)
+
+	c := m.columns()
+	if c.name != len("feature/foo") {
+		t.Errorf("name width = %d, want %d", c.name, len("feature/foo"))
+	}
+	if c.base != len("vs origin/main") {
+		t.Errorf("base width = %d", c.base)
+	}
+
+	b := Branch{Name: "feature/foo", HasRemote: true, Ahead: 1, Subject: strings.Repeat("x", 200)}
+	row := ansi.Strip(c.row(b, nil, lipgloss.NewStyle(), "", time.Now()))
+	if w := 4 + ansi.StringWidth(row); w != 120 {
+		t.Errorf("row width = %d, want 120:\n%s", w, row)
+	}
+	if !strings.Contains(row, "↑1") {
+		t.Errorf("row missing upstream divergence:\n%s", row)
+	}
+
+	m.viewport.SetWidth(60)
+	if c := m.columns(); c.subject != 0 {
+		t.Errorf("narrow viewport should hide the subject, got width %d", c.subject)
+	}
+}
+
+func TestColumns_MarkerReplacesSubject(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	c := m.columns()
+	b := Branch{Name: "local-only", Subject: "some work"}
+	row := ansi.Strip(c.row(b, nil, lipgloss.NewStyle(), "d again to delete", time.Now()))
+	if !strings.Contains(row, "d again to delete") || strings.Contains(row, "some work") {
+		t.Errorf("row = %q", row)
+	}
+}
@@ -0,0 +1,370 @@
+package gitbranch
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+var createKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("tab", "shift+tab"), key.WithHelp("tab/↑↓", "field")),
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←→", "change")),
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "create")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}}
+
+// createField is the focused line of the create form.
+type createField int
+
+const (
+	fieldTemplate createField = iota // only shown when templates are configured
+	fieldTicket                      // only shown when the template uses {ticket}
+	fieldTitle                       // only shown when the template uses {title}
+	fieldName
+	fieldStart
+	fieldRef // only shown when the start point is another ref
+	fieldSwitch
+	fieldPush
+	createFieldCount
+)
+
+// startPoint is a choice of where a new branch starts. An empty ref means
+// the user types one. fetchRemote is set when the remote-tracking branch
+// ref should be fetched first.
+type startPoint struct {
+	label       string
+	ref         string
+	fetchRemote string
+}
+
+// createOpts is everything cmdCreate needs to make a branch.
+type createOpts struct {
+	name     string
+	start    startPoint
+	push     string // remote to push to, if any
+	switchTo bool
+}
+
+// createResultMsg reports a create. pushErr is only meaningful when pushed
+// is set; the branch exists either way.
+type createResultMsg struct {
+	name     string
+	err      error
+	pushed   string
+	pushErr  error
+	switchTo bool
+}
+
+// createStartPoints lists where a new branch can start: HEAD, the default
+// branch (fetched first when it is remote), the branch under the cursor,
+// or any ref.
+func (m Model) createStartPoints() []startPoint {
+	head := "HEAD"
+	for _, b := range m.branches {
+		if b.IsCurrent {
+			head += " (" + b.Name + ")"
+		}
+	}
+	starts := []startPoint{{label: head, ref: "HEAD"}}
+	if m.base != "" {
+		p := startPoint{label: m.base, ref: m.base}
+		if remote := remoteOf(m.base, m.remoteNames); remote != "" {
+			p.label += " (fetched)"
+			p.fetchRemote = remote
+		}
+		starts = append(starts, p)
+	}
+	if b, ok := m.selected(); ok && !b.IsCurrent && b.Name != m.base {
+		starts = append(starts, startPoint{label: b.Name, ref: b.Name})
+	}
+	return append(starts, startPoint{label: "other ref…"})
+}
+
+// openCreate resets the create form and shows it.
+func (m *Model) openCreate() {
+	m.createStarts = m.createStartPoints()
+	m.createStart = 0
+	m.createRef.SetValue("")
+	m.createSwitch = false
+	m.createPush = 0
+	m.createTicket.SetValue("")
+	m.createTitle.SetValue("")
+	m.input.SetValue("")
+	m.nameCheck = nameCheckMsg{}
+	m.submitPending = false
+	m.state = stateCreate
+	m.createTemplate = 0
+	if len(m.naming.templates) > 0 {
+		m.createTemplate = 1
+		m.focusCreateField(fieldTemplate)
+		return
+	}
+	m.focusCreateField(fieldName)
+}
+
+// template returns the chosen name template, "" for none.
+func (m Model) template() string {
+	if m.createTemplate < 1 || m.createTemplate > len(m.naming.templates) {
+		return ""
+	}
+	return m.naming.templates[m.createTemplate-1]
+}
+
+// applyTemplate replaces the name with the chosen template expanded.
+func (m *Model) applyTemplate() tea.Cmd {
+	t := m.template()
+	if t == "" {
+		return nil
+	}
+	m.input.SetValue(m.naming.expand(t, m.createTicket.Value(), m.createTitle.Value()))
+	m.input.CursorEnd()
+	return m.nameChanged()
+}
+
+// createStartPoint returns the chosen start point, HEAD if there is none.
+func (m Model) createStartPoint() startPoint {
+	if m.createStart < 0 || m.createStart >= len(m.createStarts) {
+		return startPoint{label: "HEAD", ref: "HEAD"}
+	}
+	return m.createStarts[m.createStart]
+}
+
+// createFieldShown reports whether f is part of the form as it stands.
+func (m Model) createFieldShown(f createField) bool {
+	switch f {
+	case fieldTemplate:
+		return len(m.naming.templates) > 0
+	case fieldTicket:
+		return strings.Contains(m.template(), "{ticket}")
+	case fieldTitle:
+		return strings.Contains(m.template(), "{title}")
+	case fieldRef:
+		return m.createStartPoint().ref == ""
+	case fieldPush:
+		return len(m.remoteNames) > 0
+	}
+	return true
+}
+
+// focusCreateField moves the focus to f, giving the text inputs focus
+// only while their line is focused.
+func (m *Model) focusCreateField(f createField) {
+	m.createField = f
+	for _, in := range []*textinput.Model{&m.input, &m.createRef, &m.createTicket, &m.createTitle} {
+		in.Blur()
+	}
+	switch f {
+	case fieldTicket:
+		m.createTicket.Focus()
+	case fieldTitle:
+		m.createTitle.Focus()
+	case fieldName:
+		m.input.Focus()
+	case fieldRef:
+		m.createRef.Focus()
+	}
+}
+
+// moveCreateField moves the focus by delta, skipping hidden fields.
+func (m *Model) moveCreateField(delta int) {
+	f := m.createField
+	for {
+		f = (f + createField(delta) + createFieldCount) % createFieldCount
+		if m.createFieldShown(f) {
+			break
+		}
+	}
+	m.focusCreateField(f)
+}
+
+// createOptions returns the form as options, or false when it is not
+// complete: the name must be usable and another ref must be given.
+func (m Model) createOptions() (createOpts, bool) {
+	opts := createOpts{
+		name:     strings.TrimSpace(m.input.Value()),
+		start:    m.createStartPoint(),
+		switchTo: m.createSwitch,
+	}
+	if opts.start.ref == "" {
+		opts.start.ref = strings.TrimSpace(m.createRef.Value())
+		opts.start.label = opts.start.ref
+	}
+	if m.createPush > 0 && m.createPush <= len(m.remoteNames) {
+		opts.push = m.remoteNames[m.createPush-1]
+	}
+	if opts.start.ref == "" || m.nameProblem() != "" {
+		return opts, false
+	}
+	return opts, true
+}
+
+// cmdCreate fetches the start point if asked, creates the branch and pushes
+// it. Switching to it is left to cmdSwitch so local changes are handled
+// the same way as any checkout.
+func cmdCreate(opts createOpts) tea.Cmd {
+	return func() tea.Msg {
+		msg := createResultMsg{name: opts.name, switchTo: opts.switchTo}
+		if remote := opts.start.fetchRemote; remote != "" {
+			if msg.err = fetchBranch(remote, strings.TrimPrefix(opts.start.ref, remote+"/")); msg.err != nil {
+				return msg
+			}
+		}
+		if msg.err = createBranch(opts.name, opts.start.ref); msg.err != nil {
+			return msg
+		}
+		if opts.push != "" {
+			msg.pushed = opts.push
+			msg.pushErr = pushBranch(opts.push, opts.name, opts.name)
+		}
+		return msg
+	}
+}
+
+// createSummary renders the result of a create that did not switch.
+func createSummary(msg createResultMsg) string {
+	lines := []string{styles.Success.Render("✓") + " Created " + styles.Selected.Render(msg.name)}
+	switch {
+	case msg.pushed == "":
+	case msg.pushErr != nil:
+		lines = append(lines, styles.Err.Render("✗")+" Push to "+styles.Remote.Render(msg.pushed)+
+			" failed: "+styles.Err.Render(msg.pushErr.Error()))
+		if msg.switchTo {
+			lines = append(lines, styles.Dimmed.Render("Not switched to it."))
+		}
+	default:
+		lines = append(lines, styles.Success.Render("✓")+" Pushed to "+
+			styles.Remote.Render(msg.pushed+"/"+msg.name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// submitCreate creates the branch once git has checked its name.
+func (m Model) submitCreate() (tea.Model, tea.Cmd) {
+	opts, ok := m.createOptions()
+	if !ok {
+		return m, nil
+	}
+	if m, cmd, pending := m.awaitNameCheck(); pending {
+		return m, cmd
+	}
+	return startAsync(m, stateProcessing, "Creating branch...", cmdCreate(opts))
+}
+
+func (m Model) handleCreateKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateBrowse
+		m.submitPending = false
+		m.focusCreateField(fieldName)
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		return m.submitCreate()
+	case "tab", "down":
+		m.moveCreateField(1)
+		return m, nil
+	case "shift+tab", "up":
+		m.moveCreateField(-1)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	switch m.createField {
+	case fieldTemplate:
+		m.createTemplate = cycle(m.createTemplate, len(m.naming.templates)+1, msg.String())
+		cmd = m.applyTemplate()
+	case fieldTicket, fieldTitle:
+		in := &m.createTicket
+		if m.createField == fieldTitle {
+			in = &m.createTitle
+		}
+		prev := in.Value()
+		*in, cmd = in.Update(msg)
+		if in.Value() != prev {
+			cmd = tea.Batch(cmd, m.applyTemplate())
+		}
+	case fieldName:
+		return m.updateName(msg)
+	case fieldRef:
+		m.createRef, cmd = m.createRef.Update(msg)
+	case fieldStart:
+		m.createStart = cycle(m.createStart, len(m.createStarts), msg.String())
+	case fieldSwitch:
+		switch msg.String() {
+		case "left", "right", "space", "h", "l":
+			m.createSwitch = !m.createSwitch
+		}
+	case fieldPush:
+		m.createPush = cycle(m.createPush, len(m.remoteNames)+1, msg.String())
+	}
+	return m, cmd
+}
+
+// cycle steps i through n choices on ←/→ (or h/l), wrapping around.
+func cycle(i, n int, key string) int {
+	if n == 0 {
+		return i
+	}
+	switch key {
+	case "left", "h":
+		return (i - 1 + n) % n
+	case "right", "l", "space":
+		return (i + 1) % n
+	}
+	return i
+}
+
+func (m Model) createView() string {
+	content := styles.Title.Render("New Branch") + "\n\n"
+
+	label := func(f createField, s string) string {
+		if m.createField == f {
+			return styles.Selected.Render(s)
+		}
+		return styles.Dimmed.Render(s)
+	}
+	option := func(f createField, s string) string {
+		if m.createField == f {
+			return styles.Selected.Render("‹ " + s + " ›")
+		}
+		return styles.Subtitle.Render(s)
+	}
+
+	if m.createFieldShown(fieldTemplate) {
+		template := "none"
+		if t := m.template(); t != "" {
+			template = t
+		}
+		content += label(fieldTemplate, "Format: ") + option(fieldTemplate, template) + "\n"
+	}
+	if m.createFieldShown(fieldTicket) {
+		content += label(fieldTicket, "Ticket: ") + m.createTicket.View() + "\n"
+	}
+	if m.createFieldShown(fieldTitle) {
+		content += label(fieldTitle, "Title:  ") + m.createTitle.View() + "\n"
+	}
+	content += label(fieldName, "Name:   ") + m.input.View() + "\n"
+	content += label(fieldStart, "From:   ") + option(fieldStart, m.createStartPoint().label) + "\n"
+	if m.createFieldShown(fieldRef) {
+		content += label(fieldRef, "Ref:    ") + m.createRef.View() + "\n"
+	}
+	switchTo := "no"
+	if m.createSwitch {
+		switchTo = "yes"
+	}
+	content += label(fieldSwitch, "Switch: ") + option(fieldSwitch, switchTo) + "\n"
+	if m.createFieldShown(fieldPush) {
+		push := "no"
+		if m.createPush > 0 {
+			push = "--set-upstream " + m.remoteNames[m.createPush-1]
+		}
+		content += label(fieldPush, "Push:   ") + option(fieldPush, push) + "\n"
+	}
+	content += "\n"
+
+	return content + m.nameStatus() + "\n\n" + m.help.View(createKeys)
+}
@@ -0,0 +1,143 @@
+package gitbranch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func createModel() Model {
+	m := modelWithBranches(testBranches)
+	m.base = "origin/main"
+	m.remoteNames = []string{"origin", "upstream"}
+	m.cursor = 1 // feature/foo
+	m.openCreate()
+	return m
+}
+
+// typeText types s into whichever input has focus.
+func typeText(m Model, s string) Model {
+	for _, c := range s {
+		r, _ := m.Update(keyRune(c))
+		m = r.(Model)
+	}
+	return m
+}
+
+func TestCreateStartPoints(t *testing.T) {
+	m := createModel()
+	var labels []string
+	for _, p := range m.createStarts {
+		labels = append(labels, p.label)
+	}
+	want := "HEAD (main)|origin/main (fetched)|feature/foo|other ref…"
+	if got := strings.Join(labels, "|"); got != want {
+		t.Errorf("start points = %s, want %s", got, want)
+	}
+	if m.createStarts[1].fetchRemote != "origin" {
+		t.Errorf("default branch should be fetched from origin, got %q", m.createStarts[1].fetchRemote)
+	}
+}
+
+func TestCreateForm_Options(t *testing.T) {
+	m := createModel()
+	m = typeText(m, "feature/new")
+	if m.createField != fieldName || m.input.Value() != "feature/new" {
+		t.Fatalf("typing should fill the name, got %q", m.input.Value())
+	}
+
+	// From: default branch; the ref line stays hidden.
+	m = pressAll(m, keyCode(tea.KeyTab), keyCode(tea.KeyRight), keyCode(tea.KeyTab))
+	if m.createField != fieldSwitch {
+		t.Fatalf("tab should skip the hidden ref field, on %d", m.createField)
+	}
+	m = pressAll(m, keyCode(tea.KeySpace), keyCode(tea.KeyDown), keyCode(tea.KeyRight))
+
+	opts, ok := m.createOptions()
+	if !ok {
+		t.Fatal("form should be complete")
+	}
+	if opts.name != "feature/new" || opts.start.ref != "origin/main" || !opts.switchTo || opts.push != "origin" {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	if got, cmd := submitForm(m); got.state != stateProcessing || cmd == nil {
+		t.Error("enter should start the create")
+	}
+}
+
+func TestCreateForm_OtherRef(t *testing.T) {
+	m := createModel()
+	m = typeText(m, "fix")
+	m = pressAll(m, keyCode(tea.KeyTab), keyCode(tea.KeyLeft))
+	if m.createStartPoint().ref != "" {
+		t.Fatalf("← from HEAD should wrap to other ref, got %+v", m.createStartPoint())
+	}
+	if r, cmd := m.Update(keyCode(tea.KeyEnter)); r.(Model).state != stateCreate || cmd != nil {
+		t.Error("enter without a ref should do nothing")
+	}
+
+	m = pressAll(m, keyCode(tea.KeyTab))
+	if m.createField != fieldRef {
+		t.Fatalf("expected the ref field, on %d", m.createField)
+	}
+	m = typeText(m, "v1.2")
+	if opts, ok := m.createOptions(); !ok || opts.start.ref != "v1.2" {
+		t.Errorf("options = %+v, %v", opts, ok)
+	}
+	if m.input.Value() != "fix" {
+		t.Errorf("typing in the ref changed the name to %q", m.input.Value())
+	}
+}
+
+func TestCreateResultMsg_SwitchesAfterCreate(t *testing.T) {
+	m := createModel()
+	m.state = stateProcessing
+
+	r, cmd := m.Update(createResultMsg{name: "feature/new", switchTo: true, pushed: "origin"})
+	got := r.(Model)
+	if got.state != stateProcessing || got.processingMsg != "Switching branch..." || cmd == nil {
+		t.Errorf("expected a switch to start, got state %d %q", got.state, got.processingMsg)
+	}
+	if got.editing.Name != "feature/new" || got.focus != "feature/new" {
+		t.Errorf("switch target = %q, focus %q", got.editing.Name, got.focus)
+	}
+
+	r, _ = m.Update(createResultMsg{name: "feature/new", switchTo: true, pushed: "origin", pushErr: errors.New("denied")})
+	got = r.(Model)
+	if got.state != stateResult || !strings.Contains(got.result, "denied") || !strings.Contains(got.result, "Not switched") {
+		t.Errorf("a failed push should be reported without switching, got %q", got.result)
+	}
+}
+
+func TestCmdCreate_FetchesAndPushes(t *testing.T) {
+	git, origin := clonedRepo(t)
+	git("-C", origin, "commit", "-q", "--allow-empty", "-m", "newer main work")
+
+	opts := createOpts{
+		name:  "feature/new",
+		start: startPoint{ref: "origin/main", fetchRemote: "origin"},
+		push:  "origin",
+	}
+	msg := cmdCreate(opts)().(createResultMsg)
+	if msg.err != nil || msg.pushErr != nil {
+		t.Fatalf("create = %+v", msg)
+	}
+
+	branches, err := getBranches("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range branches {
+		if b.Name != "feature/new" {
+			continue
+		}
+		if b.Subject != "newer main work" || b.Upstream != "origin/feature/new" || b.IsCurrent {
+			t.Errorf("unexpected branch %+v", b)
+		}
+		return
+	}
+	t.Fatal("feature/new was not created")
+}
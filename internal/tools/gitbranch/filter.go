@@ -0,0 +1,187 @@
+package gitbranch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// branchMatch is a row of the branch list: a branch and, when filtering,
+// the rune positions in its name that matched the filter.
+type branchMatch struct {
+	Branch
+	positions []int
+	score     int
+}
+
+// visible returns the rows of the branch list. Without a filter that is
+// every branch in sort order; with one, the matching branches best first.
+// Remote-tracking branches are grouped by remote.
+func (m Model) visible() []branchMatch {
+	query := m.filter.Value()
+	list := m.list()
+	rows := make([]branchMatch, 0, len(list))
+	for _, b := range list {
+		if query == "" {
+			rows = append(rows, branchMatch{Branch: b})
+			continue
+		}
+		if score, positions, ok := fuzzyMatch(query, b.Name); ok {
+			rows = append(rows, branchMatch{Branch: b, positions: positions, score: score})
+		}
+	}
+	if query != "" {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].score > rows[j].score })
+	}
+	if m.showRemote {
+		// Group by remote, keeping the order within each group.
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Remote < rows[j].Remote })
+	}
+	return rows
+}
+
+// selected returns the branch under the cursor.
+func (m Model) selected() (Branch, bool) {
+	rows := m.visible()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return Branch{}, false
+	}
+	return rows[m.cursor].Branch, true
+}
+
+// keepCursorOn moves the cursor to the named branch if it is listed, and
+// otherwise keeps it within the list.
+func (m *Model) keepCursorOn(name string) {
+	rows := m.visible()
+	m.cursor = max(0, min(m.cursor, len(rows)-1))
+	for i, r := range rows {
+		if r.Name == name {
+			m.cursor = i
+			break
+		}
+	}
+	m.scrollToCursor()
+}
+
+// filterShown reports whether the filter line is above the list.
+func (m Model) filterShown() bool {
+	return m.filtering || m.filter.Value() != ""
+}
+
+// resizeList fits the list viewport to the window, leaving room for the
+// filter line when it is shown.
+func (m *Model) resizeList() {
+	if m.height == 0 {
+		return
+	}
+	// border(2) + padding(2) + title+blank(2) + header(1) + help+blank(2) = 9
+	h := m.height - 9
+	if m.filterShown() {
+		h--
+	}
+	m.viewport.SetHeight(h)
+}
+
+// clearFilter removes the filter, keeping the cursor on the same branch.
+func (m *Model) clearFilter() {
+	b, _ := m.selected()
+	m.filtering = false
+	m.filter.Blur()
+	m.filter.SetValue("")
+	m.resizeList()
+	m.keepCursorOn(b.Name)
+}
+
+// handleFilterKey edits the filter, re-ranking the list as the user types.
+// The arrow keys move through the results without leaving the input.
+func (m Model) handleFilterKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.filtering = false
+		m.filter.Blur()
+		m.resizeList()
+		return m, nil
+	case "esc":
+		m.clearFilter()
+		return m, nil
+	case "up":
+		if m.cursor > 0 {
+			m.cursor--
+			m.scrollToCursor()
+		}
+		return m, nil
+	case "down":
+		if m.cursor < len(m.visible())-1 {
+			m.cursor++
+			m.scrollToCursor()
+		}
+		return m, nil
+	}
+	prev := m.filter.Value()
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != prev {
+		// The best match is first; start there as the ranking changes.
+		m.cursor = 0
+		m.viewport.SetYOffset(0)
+	}
+	return m, cmd
+}
+
+// fuzzyMatch reports whether query's characters appear in order in name,
+// with a score favouring consecutive runs and matches at the start of path
+// segments or words. Matching is case-insensitive unless query contains an
+// upper-case letter. positions are rune indexes into name.
+func fuzzyMatch(query, name string) (score int, positions []int, ok bool) {
+	q, n := []rune(query), []rune(name)
+	if !strings.ContainsFunc(query, unicode.IsUpper) {
+		q, n = []rune(strings.ToLower(query)), []rune(strings.ToLower(name))
+	}
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+
+	// Greedily match from each possible start and keep the best.
+	best := -1
+	for start := range n {
+		if n[start] != q[0] {
+			continue
+		}
+		pos := []int{start}
+		for i, qi := start+1, 1; qi < len(q) && i < len(n); i++ {
+			if n[i] == q[qi] {
+				pos = append(pos, i)
+				qi++
+			}
+		}
+		if len(pos) < len(q) {
+			// Later starts can only match less.
+			break
+		}
+		if s := matchScore(n, pos); best < 0 || s > score {
+			best, score, positions = start, s, pos
+		}
+	}
+	return score, positions, best >= 0
+}
+
+// matchScore scores matched positions in name.
+func matchScore(name []rune, positions []int) int {
+	score := -min(positions[0], 3)
+	for i, p := range positions {
+		score++
+		if p == 0 || strings.ContainsRune("/-_. ", name[p-1]) {
+			score += 10
+		}
+		if i > 0 {
+			if gap := p - positions[i-1] - 1; gap == 0 {
+				score += 8
+			} else {
+				score -= gap
+			}
+		}
+	}
+	return score
+}
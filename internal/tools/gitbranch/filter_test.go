@@ -0,0 +1,147 @@
+package gitbranch
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/messages"
+)
+
+// typeFilter opens the filter and types query.
+func typeFilter(m Model, query string) Model {
+	r, _ := m.Update(keyRune('/'))
+	m = r.(Model)
+	for _, c := range query {
+		r, _ = m.Update(keyRune(c))
+		m = r.(Model)
+	}
+	return m
+}
+
+func visibleNames(m Model) []string {
+	var names []string
+	for _, r := range m.visible() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query, name string
+		ok          bool
+		positions   []int
+	}{
+		{"foo", "feature/foo", true, []int{8, 9, 10}},
+		{"ff", "feature/foo", true, []int{0, 8}},
+		{"FOO", "feature/foo", false, nil},
+		{"oof", "feature/foo", false, nil},
+		{"", "main", true, nil},
+	}
+	for _, tt := range tests {
+		_, positions, ok := fuzzyMatch(tt.query, tt.name)
+		if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = (%v, %v), want (%v, %v)",
+				tt.query, tt.name, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyMatch_RanksConsecutiveAndSegmentStarts(t *testing.T) {
+	score := func(query, name string) int {
+		s, _, ok := fuzzyMatch(query, name)
+		if !ok {
+			t.Fatalf("%q should match %q", query, name)
+		}
+		return s
+	}
+	if a, b := score("fix", "fix/login"), score("fix", "feat/index"); a <= b {
+		t.Errorf("prefix match scored %d, scattered match %d", a, b)
+	}
+	if a, b := score("log", "fix/login"), score("log", "feat/long-grid"); a <= b {
+		t.Errorf("segment match scored %d, scattered match %d", a, b)
+	}
+}
+
+func TestFilter_NarrowsAndRanks(t *testing.T) {
+	m := modelWithBranches([]Branch{
+		{Name: "feat/index"},
+		{Name: "fix/login"},
+		{Name: "main", IsCurrent: true},
+	})
+
+	m = typeFilter(m, "fix")
+	if !m.filtering {
+		t.Fatal("expected filter input to be focused")
+	}
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"fix/login", "feat/index"}) {
+		t.Errorf("visible = %v", got)
+	}
+	if m.cursor != 0 {
+		t.Errorf("cursor = %d, want the best match", m.cursor)
+	}
+
+	// Arrows move through results while typing.
+	r, _ := m.Update(keyCode(tea.KeyDown))
+	m = r.(Model)
+	if b, _ := m.selected(); b.Name != "feat/index" {
+		t.Errorf("selected %q after down", b.Name)
+	}
+}
+
+func TestFilter_ActionsUseFilteredRows(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m = typeFilter(m, "local")
+	r, _ := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.filtering || m.filter.Value() != "local" {
+		t.Fatalf("enter should keep the filter and leave the input, got filtering=%v value=%q",
+			m.filtering, m.filter.Value())
+	}
+
+	r, _ = m.Update(keyRune('e'))
+	m = r.(Model)
+	if m.state != stateEdit || m.editing.Name != "local-only" {
+		t.Errorf("expected to rename local-only, got state %d editing %q", m.state, m.editing.Name)
+	}
+}
+
+func TestFilter_ClearKeepsCursorOnBranch(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m = typeFilter(m, "foo")
+	if b, _ := m.selected(); b.Name != "feature/foo" {
+		t.Fatalf("selected %q", b.Name)
+	}
+
+	r, _ := m.Update(keyCode(tea.KeyEscape))
+	m = r.(Model)
+	if m.filtering || m.filter.Value() != "" {
+		t.Fatal("esc should clear the filter")
+	}
+	if m.cursor != 1 {
+		t.Errorf("cursor = %d, want 1 (feature/foo)", m.cursor)
+	}
+}
+
+func TestFilter_EscClearsBeforeBack(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m = typeFilter(m, "main")
+	r, _ := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+
+	r, cmd := m.Update(keyCode(tea.KeyEscape))
+	m = r.(Model)
+	if cmd != nil || m.filter.Value() != "" {
+		t.Fatal("first esc should only clear the filter")
+	}
+
+	_, cmd = m.Update(keyCode(tea.KeyEscape))
+	if cmd == nil {
+		t.Fatal("second esc should go back")
+	}
+	if _, ok := cmd().(messages.BackMsg); !ok {
+		t.Error("expected BackMsg")
+	}
+}
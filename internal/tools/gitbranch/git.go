@@ -2,23 +2,128 @@ package gitbranch
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Branch represents a local git branch with optional remote tracking info.
+// Branch represents a local git branch with optional remote tracking info,
+// or a remote-tracking branch.
 type Branch struct {
 	Name      string
 	Upstream  string
 	IsCurrent bool
 	HasRemote bool
+
+	// The upstream's remote and branch on that remote, from
+	// branch.<name>.remote and branch.<name>.merge. UpstreamRemote is "."
+	// when the upstream is a local branch.
+	UpstreamRemote string
+	UpstreamBranch string
+
+	// Tip commit.
+	CommitDate time.Time
+	Author     string
+	Subject    string
+
+	// Commits ahead of and behind the upstream. UpstreamGone is set when the
+	// upstream is configured but no longer exists on the remote.
+	Ahead, Behind int
+	UpstreamGone  bool
+
+	// Commits ahead of and behind the default branch.
+	BaseAhead, BaseBehind int
+
+	// Remote is set on remote-tracking branches, whose Name is
+	// "<remote>/<branch>". Local is the local branch that tracks it or shares
+	// its name, if any.
+	Remote string
+	Local  string
 }
 
-func getBranches() ([]Branch, error) {
+// getBranches lists local branches. Counts against the default branch are
+// filled in when base is non-empty.
+func getBranches(base string) ([]Branch, error) {
+	branches, err := listRefs("refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	countAgainst(base, "refs/heads/", branches)
+	return branches, nil
+}
+
+// listRemotes returns the names of the configured remotes.
+func listRemotes() ([]string, error) {
+	out, err := exec.Command("git", "remote").Output()
+	if err != nil {
+		return nil, fmt.Errorf("list remotes: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// getRemoteBranches lists the remote-tracking branches of remotes, linking
+// each to its local counterpart in local. Counts against the default branch
+// are filled in when base is non-empty.
+func getRemoteBranches(base string, remotes []string, local []Branch) ([]Branch, error) {
+	refs, err := listRefs("refs/remotes/")
+	if err != nil {
+		return nil, err
+	}
+	var branches []Branch
+	for _, b := range refs {
+		b.Remote = remoteOf(b.Name, remotes)
+		if b.Remote == "" || b.Name == b.Remote || b.Name == b.Remote+"/HEAD" {
+			// origin/HEAD is listed by its short name, the remote alone.
+			continue
+		}
+		b.Local = localCounterpart(b, local)
+		branches = append(branches, b)
+	}
+	countAgainst(base, "refs/remotes/", branches)
+	return branches, nil
+}
+
+// remoteOf returns the remote a remote-tracking branch name belongs to. The
+// longest match wins since remote names may contain slashes.
+func remoteOf(name string, remotes []string) string {
+	best := ""
+	for _, r := range remotes {
+		if (name == r || strings.HasPrefix(name, r+"/")) && len(r) > len(best) {
+			best = r
+		}
+	}
+	return best
+}
+
+// localCounterpart returns the local branch tracking remote, or failing
+// that the one with the same name.
+func localCounterpart(remote Branch, local []Branch) string {
+	short := strings.TrimPrefix(remote.Name, remote.Remote+"/")
+	match := ""
+	for _, l := range local {
+		if l.Upstream == remote.Name {
+			return l.Name
+		}
+		if l.Name == short {
+			match = l.Name
+		}
+	}
+	return match
+}
+
+// listRefs lists the branches under prefix.
+func listRefs(prefix string) ([]Branch, error) {
+	// Fields are NUL-separated since subjects and author names may contain
+	// any printable character.
 	cmd := exec.Command("git", "for-each-ref",
-		"--format=%(refname:short)|%(upstream:short)|%(HEAD)",
-		"refs/heads/")
+		"--format=%(refname:short)%00%(upstream:short)%00%(upstream:remotename)%00%(upstream:remoteref)%00%(HEAD)%00%(committerdate:unix)%00%(upstream:track)%00%(authorname)%00%(contents:subject)",
+		prefix)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("not a git repository or git not found")
@@ -26,22 +131,104 @@ func getBranches() ([]Branch, error) {
 
 	var branches []Branch
 	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
-		if line == "" {
-			continue
+		if b, ok := parseBranchLine(line); ok {
+			branches = append(branches, b)
 		}
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) != 3 {
+	}
+	return branches, nil
+}
+
+// countAgainst fills in the counts against base for branches, whose names
+// are relative to prefix. It does nothing when base is empty.
+func countAgainst(base, prefix string, branches []Branch) {
+	if base == "" {
+		return
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i := range branches {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			b := &branches[i]
+			b.BaseAhead, b.BaseBehind = aheadBehind(base, prefix+b.Name)
+		})
+	}
+	wg.Wait()
+}
+
+// parseBranchLine parses one line of listRefs' for-each-ref output.
+func parseBranchLine(line string) (Branch, bool) {
+	parts := strings.SplitN(line, "\x00", 9)
+	if len(parts) != 9 {
+		return Branch{}, false
+	}
+	b := Branch{
+		Name:           parts[0],
+		Upstream:       parts[1],
+		UpstreamRemote: parts[2],
+		UpstreamBranch: strings.TrimPrefix(parts[3], "refs/heads/"),
+		IsCurrent:      parts[4] == "*",
+		HasRemote:      parts[1] != "" && parts[2] != ".",
+		Author:         parts[7],
+		Subject:        parts[8],
+	}
+	if sec, err := strconv.ParseInt(parts[5], 10, 64); err == nil {
+		b.CommitDate = time.Unix(sec, 0)
+	}
+	b.Ahead, b.Behind, b.UpstreamGone = parseTrack(parts[6])
+	return b, true
+}
+
+// parseTrack parses %(upstream:track), e.g. "[ahead 2, behind 1]" or
+// "[gone]". It is empty when the branch is level with its upstream.
+func parseTrack(track string) (ahead, behind int, gone bool) {
+	track = strings.Trim(track, "[]")
+	if track == "gone" {
+		return 0, 0, true
+	}
+	for part := range strings.SplitSeq(track, ", ") {
+		kind, n, ok := strings.Cut(part, " ")
+		if !ok {
 			continue
 		}
-		b := Branch{
-			Name:      parts[0],
-			Upstream:  parts[1],
-			IsCurrent: parts[2] == "*",
-			HasRemote: parts[1] != "",
+		count, _ := strconv.Atoi(n)
+		switch kind {
+		case "ahead":
+			ahead = count
+		case "behind":
+			behind = count
 		}
-		branches = append(branches, b)
 	}
-	return branches, nil
+	return ahead, behind, false
+}
+
+// defaultBranch returns the branch others are compared against: origin's
+// HEAD when it is known, otherwise the first of main or master that exists.
+func defaultBranch() string {
+	out, err := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	for _, ref := range []string{"origin/main", "origin/master", "main", "master"} {
+		if exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run() == nil {
+			return ref
+		}
+	}
+	return ""
+}
+
+// aheadBehind counts the commits on ref but not base, and on base but not
+// ref. Errors leave both counts zero; they are informational only.
+func aheadBehind(base, ref string) (ahead, behind int) {
+	out, err := exec.Command("git", "rev-list", "--left-right", "--count", base+"..."+ref).Output()
+	if err != nil {
+		return 0, 0
+	}
+	left, right, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	behind, _ = strconv.Atoi(left)
+	ahead, _ = strconv.Atoi(right)
+	return ahead, behind
 }
 
 func renameBranch(oldName, newName string) error {
@@ -54,29 +241,221 @@ func renameBranch(oldName, newName string) error {
 	return nil
 }
 
+// trackBranch creates a local branch tracking the remote-tracking branch
+// remote and switches to it.
+func trackBranch(local, remote string) error {
+	return gitSwitch("--create", local, "--track", remote)
+}
+
 func checkoutBranch(name string) error {
+	return gitSwitch(name)
+}
+
+// dirtyTreeError is returned by gitSwitch when git refuses to switch
+// because local changes to files would be overwritten.
+type dirtyTreeError struct {
+	files []string
+}
+
+func (e *dirtyTreeError) Error() string {
+	return "local changes would be overwritten: " + strings.Join(e.files, ", ")
+}
+
+// gitSwitch runs git switch with args, returning a *dirtyTreeError when
+// local changes are in the way.
+func gitSwitch(args ...string) error {
 	var buf bytes.Buffer
-	cmd := exec.Command("git", "switch", name)
+	cmd := exec.Command("git", append([]string{"switch"}, args...)...)
+	// Untranslated messages, so the refusal can be recognised.
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
 	cmd.Stderr = &buf
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s", strings.TrimSpace(buf.String()))
+		msg := strings.TrimSpace(buf.String())
+		if files := overwrittenFiles(msg); len(files) > 0 {
+			return &dirtyTreeError{files: files}
+		}
+		return fmt.Errorf("%s", msg)
 	}
 	return nil
 }
 
-func deleteBranch(name string) error {
+// overwrittenFiles parses the files git lists when it refuses to switch
+// over local changes, tracked or untracked.
+func overwrittenFiles(stderr string) []string {
+	if !strings.Contains(stderr, "would be overwritten by checkout") {
+		return nil
+	}
+	var files []string
+	for line := range strings.SplitSeq(stderr, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			files = append(files, strings.TrimSpace(line))
+		}
+	}
+	return files
+}
+
+// stashSwitch stashes local changes, untracked files included, switches
+// with args and re-applies the stash. When re-applying conflicts the
+// conflicted files are returned and git keeps the stash. If the switch
+// itself fails the stash is re-applied where it was made. Only a stash
+// entry made here is ever popped.
+func stashSwitch(args []string) (conflicts []string, err error) {
+	before := stashTop()
 	var buf bytes.Buffer
-	cmd := exec.Command("git", "branch", "-D", name)
+	push := exec.Command("git", "stash", "push", "--include-untracked", "-m", "rig: git switch "+strings.Join(args, " "))
+	push.Stderr = &buf
+	if err := push.Run(); err != nil {
+		return nil, fmt.Errorf("stash: %s", strings.TrimSpace(buf.String()))
+	}
+	// git stash exits 0 with nothing to save, leaving older entries on top.
+	after := stashTop()
+	stashed := after != "" && after != before
+
+	if err := gitSwitch(args...); err != nil {
+		if !stashed {
+			return nil, err
+		}
+		buf.Reset()
+		pop := exec.Command("git", "stash", "pop")
+		pop.Stderr = &buf
+		if popErr := pop.Run(); popErr != nil {
+			return nil, fmt.Errorf("%w; re-applying the stash failed, your changes are kept in the stash: %s",
+				err, strings.TrimSpace(buf.String()))
+		}
+		return nil, err
+	}
+	if !stashed {
+		return nil, nil
+	}
+
+	buf.Reset()
+	pop := exec.Command("git", "stash", "pop")
+	pop.Stderr = &buf
+	if err := pop.Run(); err != nil {
+		if conflicts := conflictedFiles(); len(conflicts) > 0 {
+			return conflicts, nil
+		}
+		return nil, fmt.Errorf("switched, but re-applying the stash failed; your changes are kept in the stash: %s",
+			strings.TrimSpace(buf.String()))
+	}
+	return nil, nil
+}
+
+// stashTop returns the commit of the newest stash entry, or "" if there is
+// none.
+func stashTop() string {
+	out, err := exec.Command("git", "rev-parse", "-q", "--verify", "refs/stash").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// mergeSwitch switches with args, carrying local changes over with a
+// three-way merge. Conflicted files are returned; git leaves the conflict
+// markers in them.
+func mergeSwitch(args []string) (conflicts []string, err error) {
+	if err := gitSwitch(append([]string{"--merge"}, args...)...); err != nil {
+		return nil, err
+	}
+	return conflictedFiles(), nil
+}
+
+// conflictedFiles lists the files with unresolved merge conflicts.
+func conflictedFiles() []string {
+	out, err := exec.Command("git", "diff", "--name-only", "--diff-filter=U").Output()
+	if err != nil {
+		return nil
+	}
+	if s := strings.TrimSpace(string(out)); s != "" {
+		return strings.Split(s, "\n")
+	}
+	return nil
+}
+
+// errNotMerged is returned by deleteBranch when git refuses a safe delete
+// because the branch has commits that would become unreachable.
+var errNotMerged = errors.New("branch is not fully merged")
+
+// deleteBranch deletes a local branch. Without force it only deletes
+// branches git considers merged, returning errNotMerged otherwise.
+func deleteBranch(name string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "branch", flag, name)
+	// Untranslated messages, so the refusal can be recognised.
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
 	cmd.Stderr = &buf
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("delete branch: %s", strings.TrimSpace(buf.String()))
+		msg := strings.TrimSpace(buf.String())
+		if !force && strings.Contains(msg, "not fully merged") {
+			return errNotMerged
+		}
+		return fmt.Errorf("delete branch: %s", msg)
 	}
 	return nil
 }
 
-func createBranch(name string) error {
+// unmergedCommits lists, one line each, the commits on branch that are not
+// on base: those deleting it would make unreachable.
+func unmergedCommits(base, branch string) ([]string, error) {
+	return logOneline(base + ".." + "refs/heads/" + branch)
+}
+
+// logOneline lists commits one line each, newest first. args are passed to
+// git log.
+func logOneline(args ...string) ([]string, error) {
 	var buf bytes.Buffer
-	cmd := exec.Command("git", "branch", name)
+	cmd := exec.Command("git", append([]string{"log", "--oneline", "--no-decorate"}, args...)...)
+	cmd.Stderr = &buf
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list commits: %s", strings.TrimSpace(buf.String()))
+	}
+	if s := strings.TrimSpace(string(out)); s != "" {
+		return strings.Split(s, "\n"), nil
+	}
+	return nil, nil
+}
+
+// fileStat is a file's line counts in a diff. Binary files have no counts.
+type fileStat struct {
+	path           string
+	added, deleted int
+	binary         bool
+}
+
+// diffStat returns the files changed on ref since it forked from base.
+func diffStat(base, ref string) ([]fileStat, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "diff", "--numstat", base+"..."+ref)
+	cmd.Stderr = &buf
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("diff: %s", strings.TrimSpace(buf.String()))
+	}
+	var files []fileStat
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		f := fileStat{path: parts[2], binary: parts[0] == "-"}
+		f.added, _ = strconv.Atoi(parts[0])
+		f.deleted, _ = strconv.Atoi(parts[1])
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// createBranch creates name at start without switching to it. The new
+// branch never tracks start, even when it is a remote-tracking branch.
+func createBranch(name, start string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "branch", "--no-track", name, start)
 	cmd.Stderr = &buf
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("create branch: %s", strings.TrimSpace(buf.String()))
@@ -84,18 +463,123 @@ func createBranch(name string) error {
 	return nil
 }
 
-func renameRemoteBranch(remoteName, oldBranch, newBranch string) error {
+// fetchBranch updates the remote-tracking branch for branch on remoteName.
+func fetchBranch(remoteName, branch string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "fetch", remoteName, branch)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("fetch %s/%s: %s", remoteName, branch, strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+// pushBranch pushes the local branch name to branch on remoteName and
+// tracks it there.
+func pushBranch(remoteName, name, branch string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "push", "--set-upstream", remoteName, name+":"+branch)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("push: %s", strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+// mergedBranches returns the local branches whose tips are reachable from
+// base.
+func mergedBranches(base string) (map[string]bool, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "for-each-ref", "--merged="+base, "--format=%(refname:short)", "refs/heads/")
+	cmd.Stderr = &buf
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("merged branches: %s", strings.TrimSpace(buf.String()))
+	}
+	merged := make(map[string]bool)
+	for _, name := range strings.Fields(string(out)) {
+		merged[name] = true
+	}
+	return merged, nil
+}
+
+// branchSHA returns the commit a local branch points at.
+func branchSHA(name string) (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+name).Output()
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: not a branch", name)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// currentHead returns the checked-out branch, or the commit when HEAD is
+// detached.
+func currentHead() (head string, detached bool, err error) {
+	if out, err := exec.Command("git", "symbolic-ref", "--quiet", "--short", "HEAD").Output(); err == nil {
+		return strings.TrimSpace(string(out)), false, nil
+	}
+	out, err := exec.Command("git", "rev-parse", "--verify", "HEAD").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
+
+// restoreBranch recreates a deleted branch at sha and reports whether it
+// tracks upstream. Failing to set the upstream (the remote branch may be
+// gone too) does not fail the restore.
+func restoreBranch(name, sha, upstream string) (tracking bool, err error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "branch", name, sha)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("restore %s: %s", name, strings.TrimSpace(buf.String()))
+	}
+	if upstream == "" {
+		return false, nil
+	}
+	return exec.Command("git", "branch", "--set-upstream-to="+upstream, name).Run() == nil, nil
+}
+
+// detachAt checks out sha with a detached HEAD.
+func detachAt(sha string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "switch", "--detach", sha)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+func deleteRemoteBranch(remoteName, branch string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "push", remoteName, "--delete", branch)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("delete remote branch: %s", strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+// renameRemoteBranch pushes the branch as newBranch on toRemote and tracks
+// it there. oldBranch is deleted only when toRemote is fromRemote; a branch
+// pushed to another remote leaves the original in place.
+func renameRemoteBranch(fromRemote, oldBranch, toRemote, newBranch string) error {
 	var buf bytes.Buffer
 
 	// Push new branch first — if this fails, old branch is still intact.
-	pushCmd := exec.Command("git", "push", "--set-upstream", remoteName, newBranch)
+	pushCmd := exec.Command("git", "push", "--set-upstream", toRemote, newBranch)
 	pushCmd.Stderr = &buf
 	if err := pushCmd.Run(); err != nil {
 		return fmt.Errorf("push new branch: %s", strings.TrimSpace(buf.String()))
 	}
+	if fromRemote != toRemote {
+		return nil
+	}
 
 	buf.Reset()
-	delCmd := exec.Command("git", "push", remoteName, "--delete", oldBranch)
+	delCmd := exec.Command("git", "push", fromRemote, "--delete", oldBranch)
 	delCmd.Stderr = &buf
 	if err := delCmd.Run(); err != nil {
 		return fmt.Errorf("delete old remote branch: %s", strings.TrimSpace(buf.String()))
@@ -103,3 +587,26 @@ func renameRemoteBranch(remoteName, oldBranch, newBranch string) error {
 
 	return nil
 }
+
+// fastForward updates the local branch name from branch on remoteName
+// without checking it out. git refuses if it is not a fast-forward.
+func fastForward(remoteName, branch, name string) error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "fetch", remoteName, branch+":"+name)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("fast-forward: %s", strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+// pullFastForward fast-forwards the current branch from its upstream.
+func pullFastForward() error {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "pull", "--ff-only")
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pull: %s", strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
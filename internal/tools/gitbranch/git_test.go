@@ -0,0 +1,134 @@
+package gitbranch
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestParseTrack(t *testing.T) {
+	tests := []struct {
+		track         string
+		ahead, behind int
+		gone          bool
+	}{
+		{"", 0, 0, false},
+		{"[ahead 2]", 2, 0, false},
+		{"[behind 3]", 0, 3, false},
+		{"[ahead 2, behind 1]", 2, 1, false},
+		{"[gone]", 0, 0, true},
+	}
+	for _, tt := range tests {
+		ahead, behind, gone := parseTrack(tt.track)
+		if ahead != tt.ahead || behind != tt.behind || gone != tt.gone {
+			t.Errorf("parseTrack(%q) = (%d, %d, %v), want (%d, %d, %v)",
+				tt.track, ahead, behind, gone, tt.ahead, tt.behind, tt.gone)
+		}
+	}
+}
+
+func TestParseBranchLine(t *testing.T) {
+	b, ok := parseBranchLine("feature/x\x00team/ada/theirs\x00team/ada\x00refs/heads/theirs\x00*\x001700000000\x00[ahead 1]\x00Ada Lovelace\x00fix: a | b")
+	if !ok {
+		t.Fatal("expected line to parse")
+	}
+	if b.Name != "feature/x" || !b.IsCurrent || !b.HasRemote || b.Ahead != 1 {
+		t.Errorf("unexpected branch %+v", b)
+	}
+	if b.UpstreamRemote != "team/ada" || b.UpstreamBranch != "theirs" {
+		t.Errorf("upstream = %q on %q, want theirs on team/ada", b.UpstreamBranch, b.UpstreamRemote)
+	}
+	if b.Author != "Ada Lovelace" || b.Subject != "fix: a | b" || b.CommitDate.Unix() != 1700000000 {
+		t.Errorf("unexpected metadata %+v", b)
+	}
+
+	// A local upstream is not a remote.
+	b, _ = parseBranchLine("topic\x00main\x00.\x00refs/heads/main\x00 \x00\x00\x00\x00")
+	if b.Upstream != "main" || b.HasRemote {
+		t.Errorf("local upstream parsed as %+v", b)
+	}
+
+	if _, ok := parseBranchLine("main|origin/main|*"); ok {
+		t.Error("expected a malformed line to be skipped")
+	}
+}
+
+// gitRepo creates a repository in a temp dir with main one commit behind
+// and one commit ahead of topic, and makes it the working directory.
+func gitRepo(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	git := gittest.Git(t)
+	git("init", "-q", "-b", "main")
+	git("commit", "-q", "--allow-empty", "-m", "root")
+	git("switch", "-q", "-c", "topic")
+	git("commit", "-q", "--allow-empty", "-m", "topic work")
+	git("switch", "-q", "main")
+	git("commit", "-q", "--allow-empty", "-m", "main work")
+}
+
+// clonedRepo clones a gitRepo, leaving the clone in the working directory on
+// main, and returns a git runner and the origin's path.
+func clonedRepo(t *testing.T) (git func(args ...string), origin string) {
+	t.Helper()
+	gitRepo(t)
+	origin, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	git = gittest.Git(t)
+	t.Chdir(t.TempDir())
+	git("clone", "-q", origin, ".")
+	return git, origin
+}
+
+func TestGetBranches_CountsAgainstDefaultBranch(t *testing.T) {
+	gitRepo(t)
+
+	base := defaultBranch()
+	if base != "main" {
+		t.Fatalf("defaultBranch() = %q, want main", base)
+	}
+	branches, err := getBranches(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("got %d branches, want 2", len(branches))
+	}
+	topic := branches[1]
+	if topic.Name != "topic" || topic.Subject != "topic work" || topic.Author != "t" {
+		t.Errorf("unexpected branch %+v", topic)
+	}
+	if topic.BaseAhead != 1 || topic.BaseBehind != 1 {
+		t.Errorf("topic vs main = +%d -%d, want +1 -1", topic.BaseAhead, topic.BaseBehind)
+	}
+	if topic.CommitDate.IsZero() {
+		t.Error("expected a commit date")
+	}
+}
+
+func TestDeleteBranch_RefusesUnmerged(t *testing.T) {
+	gitRepo(t)
+
+	if err := deleteBranch("topic", false); !errors.Is(err, errNotMerged) {
+		t.Fatalf("safe delete of an unmerged branch = %v, want errNotMerged", err)
+	}
+	commits, err := unmergedCommits("main", "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || !strings.HasSuffix(commits[0], " topic work") {
+		t.Errorf("unmerged commits = %q", commits)
+	}
+
+	if err := deleteBranch("topic", true); err != nil {
+		t.Fatalf("forced delete: %v", err)
+	}
+	if err := deleteBranch("missing", false); err == nil || errors.Is(err, errNotMerged) {
+		t.Errorf("deleting a missing branch = %v, want a plain error", err)
+	}
+}
@@ -1,7 +1,10 @@
 package gitbranch
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,7 +26,7 @@ func init() {
 	registry.Register(registry.Tool{
 		ID:          "git-branch",
 		Name:        "git-branch",
-		Description: "Rename git branches (local and remote)",
+		Description: "Checkout, rename, create, delete, push and fast-forward git branches",
 		New:         func() tea.Model { return New() },
 	})
 }
@@ -38,6 +41,10 @@ const (
 	stateConfirmRemote
 	stateProcessing
 	stateResult
+	stateCleanup
+	stateConfirmForce
+	stateDirtyCheckout
+	statePickRemote
 )
 
 type keyMap struct {
@@ -52,17 +59,34 @@ var browseKeys = keyMap{bindings: []key.Binding{
 	key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "rename")),
 	key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "create")),
 	key.NewBinding(key.WithKeys("d"), key.WithHelp("dd", "delete")),
+	key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "push")),
+	key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "fast-forward")),
+	key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "clean up")),
+	key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo")),
+	key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "local/remote")),
+	key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+	key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
 	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
 }}
 
-var editKeys = keyMap{bindings: []key.Binding{
+var remoteKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "track & checkout")),
+	key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "local/remote")),
+	key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+	key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
+	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
+}}
+
+var filterKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑↓", "move")),
 	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
-	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear")),
 }}
 
-var createKeys = keyMap{bindings: []key.Binding{
-	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "create")),
+var editKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
 }}
 
@@ -73,6 +97,11 @@ var confirmRemoteKeys = keyMap{bindings: []key.Binding{
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
 }}
 
+var confirmForceKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "force delete")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/n", "keep branch")),
+}}
+
 var resultKeys = keyMap{bindings: []key.Binding{
 	key.NewBinding(key.WithKeys("any"), key.WithHelp("any key", "refresh")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
@@ -83,8 +112,13 @@ var dismissKeys = keyMap{bindings: []key.Binding{
 }}
 
 type branchesLoadedMsg struct {
-	branches []Branch
-	err      error
+	branches    []Branch
+	remotes     []Branch
+	remoteNames []string
+	naming      namingRules
+	namingErr   error  // the naming config could not be read in full
+	base        string // default branch the counts are against; empty if unknown
+	err         error
 }
 
 type renameResultMsg struct {
@@ -93,21 +127,51 @@ type renameResultMsg struct {
 	err      error
 }
 
-type deleteResultMsg struct{ err error }
-type createResultMsg struct{ err error }
-type checkoutResultMsg struct{ err error }
+// deleteResultMsg reports a delete. When git refused a safe delete, err is
+// errNotMerged and commits lists the work that a forced delete would lose.
+type deleteResultMsg struct {
+	err     error
+	commits []string
+	undo    *undoOp
+}
+
+// checkoutResultMsg reports a switch. conflicts lists files left
+// conflicted by carrying local changes over; stashed is set when those
+// changes are also still in the stash.
+type checkoutResultMsg struct {
+	err       error
+	undo      *undoOp
+	conflicts []string
+	stashed   bool
+}
 
 // Model is the git branch editor TUI model.
+//
+// Models are passed by value, so handlers build new slices and maps instead
+// of editing shared ones in place.
 type Model struct {
 	state         viewState
 	branches      []Branch
-	cursor        int
+	remotes       []Branch // remote-tracking branches
+	remoteNames   []string
+	showRemote    bool   // list remotes instead of branches
+	focus         string // branch to put the cursor on after the next load
+	base          string
+	sort          sortMode
+	cursor        int // row in visible()
+	filter        textinput.Model
+	filtering     bool // filter input has focus
 	input         textinput.Model
+	nameCheck     nameCheckMsg // git's verdict on the last name typed
+	submitPending bool         // submit the name form once nameCheck arrives
+	naming        namingRules
+	namingErr     error              // shown on the name forms
+	previews      map[string]preview // by previewRef
 	editing       Branch
-	didRemote     bool
+	renameRemote  string // remote the upstream was renamed on; "" = local only
 	result        string
 	errSplash     string // non-empty = show error splash; any key dismisses it
-	confirmIdx    int    // 0 = yes, 1 = no
+	confirmIdx    int    // index of the highlighted choice in a confirm screen
 	spinner       spinner.Model
 	stopwatch     stopwatch.Model
 	help          help.Model
@@ -118,12 +182,32 @@ type Model struct {
 	// delete staging — first d marks, second d confirms
 	deleteStaged    bool
 	deleteStagedIdx int
+	unmerged        []string // commits shown before a forced delete
+	undo            []undoOp // most recent last
+	dirtyFiles      []string // local changes blocking a checkout
+	// cleanup: see cleanup.go
+	merged          map[string]bool // branches merged into base
+	cleanup         []cleanupItem
+	cleanupCursor   int
+	cleanupViewport viewport.Model
+	inactiveStep    int // index into inactiveSteps
+	cleanupRemote   bool
+	cleanupResults  []cleanupResult  // deletes done so far in the batch
+	cleanupUnmerged []unmergedBranch // awaiting a forced delete
+	// create: see create.go
+	createTemplate int // 0 = none, otherwise naming.templates[createTemplate-1]
+	createTicket   textinput.Model
+	createTitle    textinput.Model
+	createStarts   []startPoint
+	createStart    int // index into createStarts
+	createRef      textinput.Model
+	createField    createField
+	createSwitch   bool
+	createPush     int // 0 = don't push, otherwise remoteNames[createPush-1]
 }
 
 func New() Model {
-	ti := textinput.New()
-	ti.CharLimit = 200
-	ti.SetWidth(50)
+	ti := newInput()
 
 	s := spinner.New()
 	s.Spinner = spinner.MiniDot
@@ -136,26 +220,57 @@ func New() Model {
 	h.Styles.ShortDesc = styles.Help
 	h.Styles.ShortSeparator = styles.Help
 
+	fi := newInput()
+	fi.Prompt = "/"
+
 	vp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
 	vp.KeyMap = viewport.KeyMap{}
+	cvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	cvp.KeyMap = viewport.KeyMap{}
 
 	return Model{
 		state:     stateLoading,
 		input:     ti,
+		filter:    fi,
 		spinner:   s,
 		stopwatch: sw,
 		help:      h,
 		viewport:  vp,
+
+		cleanupViewport: cvp,
+		inactiveStep:    defaultInactiveStep,
+
+		createRef:    newInput(),
+		createTicket: newInput(),
+		createTitle:  newInput(),
 	}
 }
 
+func newInput() textinput.Model {
+	ti := textinput.New()
+	ti.CharLimit = 200
+	ti.SetWidth(50)
+	return ti
+}
+
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(fetchBranches, m.spinner.Tick, m.stopwatch.Start())
 }
 
 func fetchBranches() tea.Msg {
-	branches, err := getBranches()
-	return branchesLoadedMsg{branches: branches, err: err}
+	// Bad naming config is reported on the name forms, not as a load error.
+	naming, namingErr := loadNaming()
+	base := defaultBranch()
+	branches, err := getBranches(base)
+	if err != nil {
+		return branchesLoadedMsg{err: err}
+	}
+	remoteNames, err := listRemotes()
+	if err != nil {
+		return branchesLoadedMsg{err: err}
+	}
+	remotes, err := getRemoteBranches(base, remoteNames, branches)
+	return branchesLoadedMsg{branches: branches, remotes: remotes, remoteNames: remoteNames, naming: naming, namingErr: namingErr, base: base, err: err}
 }
 
 // startAsync transitions into a waiting state, resets the timer, and
@@ -188,50 +303,69 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		// border(2) + padding(2) horizontal on each side
-		m.viewport.SetWidth(msg.Width - 6)
-		// border(2) + padding(2) + title+blank(2) + help+blank(2) = 8
-		m.viewport.SetHeight(msg.Height - 8)
-		return m, nil
+		m.viewport.SetWidth(msg.Width - 6 - m.previewWidth())
+		m.resizeList()
+		m.cleanupViewport.SetWidth(msg.Width - 6)
+		// border(2) + padding(2) + title+blank(2) + settings+blank(2) + count(1) + help+blank(2) = 11
+		m.cleanupViewport.SetHeight(msg.Height - 11)
+		return withPreview(m, nil)
 
 	case branchesLoadedMsg:
 		if msg.err != nil {
 			m = showError(m, msg.err)
 		} else {
+			focus := m.focus
+			if focus == "" {
+				prev, _ := m.selected()
+				focus = prev.Name
+			}
 			m.state = stateBrowse
 			m.branches = msg.branches
-			if m.cursor >= len(m.branches) && len(m.branches) > 0 {
-				m.cursor = len(m.branches) - 1
-			}
+			m.remotes = msg.remotes
+			m.remoteNames = msg.remoteNames
+			m.naming = msg.naming
+			m.namingErr = msg.namingErr
+			m.previews = nil
+			m.base = msg.base
+			m.focus = ""
+			sortBranches(m.branches, m.sort)
+			sortBranches(m.remotes, m.sort)
+			m.keepCursorOn(focus)
 		}
-		return m, nil
+		return withPreview(m, nil)
 
 	case renameResultMsg:
+		newName := strings.TrimSpace(m.input.Value())
 		if msg.err != nil {
 			if msg.localOk {
 				// Local succeeded but remote failed — show partial result.
+				m.pushUndo(undoOp{kind: undoRename, oldName: m.editing.Name, newName: newName})
 				m.state = stateResult
-				remote, _ := splitUpstream(m.editing.Upstream)
 				m.result = styles.Success.Render("✓") + " Renamed " +
 					styles.Dimmed.Render(m.editing.Name) + " → " +
-					styles.Selected.Render(m.input.Value()) + "\n" +
+					styles.Selected.Render(newName) + "\n" +
 					styles.Err.Render("✗") + " Remote " +
-					styles.Remote.Render(remote) + " update failed: " +
+					styles.Remote.Render(m.renameRemote) + " update failed: " +
 					styles.Err.Render(msg.err.Error())
 			} else {
 				m = showError(m, msg.err)
 			}
 		} else {
+			m.pushUndo(undoOp{kind: undoRename, oldName: m.editing.Name, newName: newName})
 			m.state = stateResult
 			lines := []string{
 				styles.Success.Render("✓") + " Renamed " +
 					styles.Dimmed.Render(m.editing.Name) + " → " +
-					styles.Selected.Render(m.input.Value()),
+					styles.Selected.Render(newName),
 			}
-			if m.didRemote && msg.remoteOk {
-				remote, _ := splitUpstream(m.editing.Upstream)
+			if m.renameRemote != "" && msg.remoteOk {
+				verb := " Updated remote "
+				if m.renameRemote != m.editing.UpstreamRemote {
+					verb = " Pushed to "
+				}
 				lines = append(lines,
-					styles.Success.Render("✓")+" Updated remote "+
-						styles.Remote.Render(remote+"/"+m.input.Value()),
+					styles.Success.Render("✓")+verb+
+						styles.Remote.Render(m.renameRemote+"/"+newName),
 				)
 			}
 			m.result = strings.Join(lines, "\n")
@@ -239,35 +373,129 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case deleteResultMsg:
-		if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, errNotMerged):
+			m.state = stateConfirmForce
+			m.unmerged = msg.commits
+		case msg.err != nil:
 			m = showError(m, msg.err)
-		} else {
+		default:
+			if msg.undo != nil {
+				m.pushUndo(*msg.undo)
+			}
 			m.state = stateResult
 			m.result = styles.Success.Render("✓") + " Deleted " +
 				styles.Dimmed.Render(m.editing.Name)
 		}
 		return m, nil
 
+	case previewLoadedMsg:
+		if _, ok := m.previews[msg.ref]; ok {
+			previews := maps.Clone(m.previews)
+			previews[msg.ref] = msg.preview
+			m.previews = previews
+		}
+		return m, nil
+
+	case syncResultMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.focus = msg.name
+		m.state = stateResult
+		m.result = syncSummary(msg)
+		return m, nil
+
+	case nameCheckMsg:
+		if msg.name != strings.TrimSpace(m.input.Value()) {
+			return m, nil
+		}
+		m.nameCheck = msg
+		if m.submitPending {
+			m.submitPending = false
+			return m.submitName()
+		}
+		return m, nil
+
 	case createResultMsg:
 		if msg.err != nil {
 			m = showError(m, msg.err)
-		} else {
-			m.state = stateResult
-			m.result = styles.Success.Render("✓") + " Created " +
-				styles.Selected.Render(m.input.Value())
+			return m, nil
 		}
+		m.focus = msg.name
+		if msg.switchTo && msg.pushErr == nil {
+			m.editing = Branch{Name: msg.name}
+			return startAsync(m, stateProcessing, "Switching branch...", cmdSwitch(m.editing, switchPlain))
+		}
+		m.state = stateResult
+		m.result = createSummary(msg)
 		return m, nil
 
 	case checkoutResultMsg:
+		var dirty *dirtyTreeError
+		if errors.As(msg.err, &dirty) {
+			m.state = stateDirtyCheckout
+			m.dirtyFiles = dirty.files
+			m.confirmIdx = 0
+			return m, nil
+		}
 		if msg.err != nil {
 			m = showError(m, msg.err)
 			return m, nil
 		}
+		if msg.undo != nil {
+			m.pushUndo(*msg.undo)
+		}
+		if len(msg.conflicts) > 0 {
+			m.state = stateResult
+			m.result = conflictSummary(m.editing, msg.conflicts, msg.stashed)
+			return m, nil
+		}
+		if m.editing.Remote != "" {
+			// Show the local branch that was checked out.
+			m.showRemote = false
+			m.focus = localName(m.editing)
+		}
 		// Reload so the current-branch indicator updates.
 		return startAsync(m, stateLoading, "Loading branches...", fetchBranches)
 
+	case mergedLoadedMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.state = stateCleanup
+		m.merged = msg.merged
+		m.cleanup = nil
+		m.cleanupCursor = 0
+		m.refreshCleanup()
+		return m, nil
+
+	case cleanupDoneMsg:
+		if msg.undo != nil {
+			m.pushUndo(*msg.undo)
+		}
+		m.cleanupResults = append(slices.Clip(m.cleanupResults), msg.results...)
+		m.cleanupUnmerged = msg.unmerged
+		if len(msg.unmerged) > 0 {
+			m.state = stateConfirmForce
+			return m, nil
+		}
+		return m.finishCleanup(), nil
+
+	case undoResultMsg:
+		if msg.err != nil {
+			m = showError(m, fmt.Errorf("undo %s: %w", msg.op, msg.err))
+			return m, nil
+		}
+		m.undo = m.undo[:len(m.undo)-1]
+		m.state = stateResult
+		m.result = undoSummary(msg.op, msg.untracked)
+		return m, nil
+
 	case tea.KeyPressMsg:
-		return m.handleKey(msg)
+		return withPreview(m.handleKey(msg))
 	}
 
 	// Route spinner and stopwatch messages when in async states.
@@ -287,60 +515,102 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	switch m.state {
 	case stateBrowse:
+		if m.filtering {
+			m.deleteStaged = false
+			return m.handleFilterKey(msg)
+		}
 		// Any key other than d clears delete staging.
 		if msg.String() != "d" {
 			m.deleteStaged = false
 		}
 
 		switch msg.String() {
-		case "q", "esc":
+		case "esc":
+			// Clear an active filter before leaving.
+			if m.filter.Value() != "" {
+				m.clearFilter()
+				return m, nil
+			}
+			return m, func() tea.Msg { return messages.BackMsg{} }
+		case "q":
 			return m, func() tea.Msg { return messages.BackMsg{} }
+		case "/":
+			m.filtering = true
+			m.filter.CursorEnd()
+			m.resizeList()
+			return m, m.filter.Focus()
+		case "tab":
+			m.showRemote = !m.showRemote
+			m.cursor = 0
+			m.scrollToCursor()
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
-				ensureCursorVisible(&m.viewport, m.cursor)
+				m.scrollToCursor()
 			}
 		case "down", "j":
-			if m.cursor < len(m.branches)-1 {
+			if m.cursor < len(m.visible())-1 {
 				m.cursor++
-				ensureCursorVisible(&m.viewport, m.cursor)
+				m.scrollToCursor()
 			}
 		case "enter":
-			if len(m.branches) > 0 {
-				b := m.branches[m.cursor]
+			if b, ok := m.selected(); ok {
 				if b.IsCurrent {
 					break
 				}
 				m.editing = b
-				return startAsync(m, stateProcessing, "Switching branch...", m.cmdCheckout(b.Name))
+				return startAsync(m, stateProcessing, "Switching branch...", cmdSwitch(b, switchPlain))
 			}
 		case "e":
-			if len(m.branches) > 0 {
-				m.editing = m.branches[m.cursor]
+			if b, ok := m.selected(); ok && b.Remote == "" {
+				m.editing = b
 				m.input.SetValue(m.editing.Name)
 				m.input.Focus()
 				m.input.CursorEnd()
+				m.nameCheck = nameCheckMsg{name: b.Name}
+				m.submitPending = false
 				m.state = stateEdit
 			}
 		case "c":
-			m.input.SetValue("")
-			m.input.Focus()
-			m.state = stateCreate
+			m.openCreate()
 		case "d":
-			if len(m.branches) == 0 {
-				break
-			}
-			b := m.branches[m.cursor]
-			if b.IsCurrent {
+			b, ok := m.selected()
+			if !ok || b.IsCurrent || b.Remote != "" {
 				break
 			}
 			if m.deleteStaged && m.deleteStagedIdx == m.cursor {
 				m.editing = b
 				m.deleteStaged = false
-				return startAsync(m, stateProcessing, "Deleting branch...", m.cmdDelete(b.Name))
+				return startAsync(m, stateProcessing, "Deleting branch...", m.cmdDelete(b.Name, false))
 			}
 			m.deleteStaged = true
 			m.deleteStagedIdx = m.cursor
+		case "p":
+			if b, ok := m.selected(); ok && b.Remote == "" {
+				return m.startPush(b)
+			}
+		case "f":
+			if b, ok := m.selected(); ok && b.Remote == "" {
+				return startAsync(m, stateProcessing, "Fast-forwarding "+b.Name+"...", cmdFastForward(b))
+			}
+		case "x":
+			if m.showRemote {
+				break
+			}
+			if m.base == "" {
+				m = showError(m, fmt.Errorf("no default branch found to check merged branches against"))
+				break
+			}
+			return startAsync(m, stateLoading, "Finding stale branches...", fetchMerged(m.base))
+		case "u":
+			if len(m.undo) == 0 {
+				break
+			}
+			op := m.undo[len(m.undo)-1]
+			return startAsync(m, stateProcessing, "Undoing "+op.String()+"...", cmdUndo(op))
+		case "s":
+			m.sort = m.sort.next()
+			m.sortKeepingCursor()
 		case "r":
 			return startAsync(m, stateLoading, "Loading branches...", fetchBranches)
 		}
@@ -349,70 +619,39 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "esc":
 			m.state = stateBrowse
+			m.submitPending = false
 			m.input.Blur()
 			return m, nil
 		case "enter":
-			newName := strings.TrimSpace(m.input.Value())
-			if newName == "" || newName == m.editing.Name {
-				m.state = stateBrowse
-				m.input.Blur()
-				return m, nil
-			}
-			if m.editing.HasRemote {
-				m.state = stateConfirmRemote
-				m.confirmIdx = 0
-				return m, nil
-			}
-			return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameLocal(newName))
+			return m.submitRename()
 		default:
-			var inputCmd tea.Cmd
-			m.input, inputCmd = m.input.Update(msg)
-			return m, inputCmd
+			return m.updateName(msg)
 		}
 
 	case stateCreate:
-		switch msg.String() {
-		case "esc":
-			m.state = stateBrowse
-			m.input.Blur()
-			return m, nil
-		case "enter":
-			newName := strings.TrimSpace(m.input.Value())
-			if newName == "" || m.branchExists(newName) {
-				return m, nil
-			}
-			return startAsync(m, stateProcessing, "Creating branch...", m.cmdCreate(newName))
-		default:
-			var inputCmd tea.Cmd
-			m.input, inputCmd = m.input.Update(msg)
-			return m, inputCmd
-		}
+		return m.handleCreateKey(msg)
 
 	case stateConfirmRemote:
+		return m.handleConfirmRemoteKey(msg)
+
+	case statePickRemote:
+		return m.handlePickRemoteKey(msg)
+
+	case stateCleanup:
+		return m.handleCleanupKey(msg)
+
+	case stateDirtyCheckout:
+		return m.handleDirtyKey(msg)
+
+	case stateConfirmForce:
+		if len(m.cleanupUnmerged) > 0 {
+			return m.handleForceCleanupKey(msg)
+		}
 		switch msg.String() {
-		case "esc":
+		case "esc", "n", "q":
 			m.state = stateBrowse
-			return m, nil
-		case "left", "h", "shift+tab":
-			m.confirmIdx = 0
-		case "right", "l", "tab":
-			m.confirmIdx = 1
-		case "y":
-			newName := strings.TrimSpace(m.input.Value())
-			m.didRemote = true
-			return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameAll(newName))
-		case "n":
-			newName := strings.TrimSpace(m.input.Value())
-			m.didRemote = false
-			return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameLocal(newName))
-		case "enter", "space":
-			newName := strings.TrimSpace(m.input.Value())
-			if m.confirmIdx == 0 {
-				m.didRemote = true
-				return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameAll(newName))
-			}
-			m.didRemote = false
-			return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameLocal(newName))
+		case "D":
+			return startAsync(m, stateProcessing, "Deleting branch...", m.cmdDelete(m.editing.Name, true))
 		}
 
 	case stateResult:
@@ -428,6 +667,17 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// sortKeepingCursor re-sorts the branches, keeping the cursor on the same
+// branch.
+func (m *Model) sortKeepingCursor() {
+	b, _ := m.selected()
+	m.branches = append([]Branch(nil), m.branches...)
+	m.remotes = append([]Branch(nil), m.remotes...)
+	sortBranches(m.branches, m.sort)
+	sortBranches(m.remotes, m.sort)
+	m.keepCursorOn(b.Name)
+}
+
 func (m Model) branchExists(name string) bool {
 	for _, b := range m.branches {
 		if b.Name == name {
@@ -437,10 +687,27 @@ func (m Model) branchExists(name string) bool {
 	return false
 }
 
-func (m Model) cmdCheckout(name string) tea.Cmd {
-	return func() tea.Msg {
-		return checkoutResultMsg{err: checkoutBranch(name)}
+// submitRename renames the branch once git has checked the new name, asking
+// first whether to rename its upstream too.
+func (m Model) submitRename() (tea.Model, tea.Cmd) {
+	newName := strings.TrimSpace(m.input.Value())
+	if newName == "" || newName == m.editing.Name {
+		m.state = stateBrowse
+		m.input.Blur()
+		return m, nil
+	}
+	if m.nameProblem() != "" {
+		return m, nil
+	}
+	if m, cmd, pending := m.awaitNameCheck(); pending {
+		return m, cmd
+	}
+	if m.editing.HasRemote {
+		m.state = stateConfirmRemote
+		m.confirmIdx = 0
+		return m, nil
 	}
+	return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameLocal(newName))
 }
 
 func (m Model) cmdRenameLocal(newName string) tea.Cmd {
@@ -451,28 +718,47 @@ func (m Model) cmdRenameLocal(newName string) tea.Cmd {
 	}
 }
 
-func (m Model) cmdRenameAll(newName string) tea.Cmd {
+// cmdRenameAll renames the branch and pushes it as newName on toRemote. The
+// old upstream is deleted only when it is on toRemote.
+func (m Model) cmdRenameAll(newName, toRemote string) tea.Cmd {
 	oldName := m.editing.Name
-	upstream := m.editing.Upstream
+	fromRemote, oldBranch := m.editing.UpstreamRemote, m.editing.UpstreamBranch
 	return func() tea.Msg {
 		if err := renameBranch(oldName, newName); err != nil {
 			return renameResultMsg{err: err}
 		}
-		remote, branch := splitUpstream(upstream)
-		err := renameRemoteBranch(remote, branch, newName)
+		err := renameRemoteBranch(fromRemote, oldBranch, toRemote, newName)
 		return renameResultMsg{localOk: true, remoteOk: err == nil, err: err}
 	}
 }
 
-func (m Model) cmdDelete(name string) tea.Cmd {
-	return func() tea.Msg {
-		return deleteResultMsg{err: deleteBranch(name)}
+// cmdDelete deletes a branch, refusing when it is not merged unless force
+// is set. Refusals carry the commits that would be lost, relative to the
+// default branch when known.
+func (m Model) cmdDelete(name string, force bool) tea.Cmd {
+	base := m.base
+	if base == "" {
+		base = "HEAD"
 	}
-}
-
-func (m Model) cmdCreate(name string) tea.Cmd {
+	upstream := m.editing.Upstream
 	return func() tea.Msg {
-		return createResultMsg{err: createBranch(name)}
+		sha, err := branchSHA(name)
+		if err != nil {
+			return deleteResultMsg{err: err}
+		}
+		err = deleteBranch(name, force)
+		if err == nil {
+			undo := undoOp{kind: undoDelete, deleted: []deletedBranch{{name: name, sha: sha, upstream: upstream}}}
+			return deleteResultMsg{undo: &undo}
+		}
+		if !errors.Is(err, errNotMerged) {
+			return deleteResultMsg{err: err}
+		}
+		commits, logErr := unmergedCommits(base, name)
+		if logErr != nil {
+			return deleteResultMsg{err: logErr}
+		}
+		return deleteResultMsg{err: err, commits: commits}
 	}
 }
 
@@ -484,13 +770,92 @@ func ensureCursorVisible(vp *viewport.Model, cursor int) {
 	}
 }
 
-// splitUpstream splits "origin/feature/foo" into ("origin", "feature/foo").
-func splitUpstream(upstream string) (remote, branch string) {
-	before, after, ok := strings.Cut(upstream, "/")
-	if !ok {
-		return upstream, upstream
+// renameChoices lists the remotes the upstream can be renamed on, its
+// current remote first, followed by keeping the rename local.
+func (m Model) renameChoices() []string {
+	return append(remoteChoices(m.editing, m.remoteNames), "No")
+}
+
+func (m Model) handleConfirmRemoteKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	choices := m.renameChoices()
+	rename := func(i int) (tea.Model, tea.Cmd) {
+		newName := strings.TrimSpace(m.input.Value())
+		if i == len(choices)-1 {
+			m.renameRemote = ""
+			return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameLocal(newName))
+		}
+		m.renameRemote = choices[i]
+		return startAsync(m, stateProcessing, "Renaming branch...", m.cmdRenameAll(newName, m.renameRemote))
+	}
+	switch msg.String() {
+	case "esc":
+		m.state = stateBrowse
+	case "left", "h", "shift+tab":
+		m.confirmIdx = max(0, m.confirmIdx-1)
+	case "right", "l", "tab":
+		m.confirmIdx = min(len(choices)-1, m.confirmIdx+1)
+	case "y":
+		return rename(0)
+	case "n":
+		return rename(len(choices) - 1)
+	case "enter", "space":
+		return rename(m.confirmIdx)
+	}
+	return m, nil
+}
+
+func (m Model) confirmRemoteView() string {
+	newName := strings.TrimSpace(m.input.Value())
+	choices := m.renameChoices()
+	remote := choices[0]
+	if m.confirmIdx < len(choices)-1 {
+		remote = choices[m.confirmIdx]
+	}
+
+	content := styles.Title.Render("Update Remote?") + "\n\n"
+	if remote == m.editing.UpstreamRemote {
+		content += fmt.Sprintf("Also rename %s\n         → %s?\n\n",
+			styles.Remote.Render(m.editing.Upstream),
+			styles.Selected.Render(remote+"/"+newName),
+		)
+	} else {
+		content += fmt.Sprintf("Also push to %s?\n%s\n\n",
+			styles.Selected.Render(remote+"/"+newName),
+			styles.Dimmed.Render(m.editing.Upstream+" is kept; the branch will track the new remote"),
+		)
 	}
-	return before, after
+	labels := slices.Clone(choices)
+	if len(labels) == 2 {
+		labels[0] = "Yes"
+	}
+	content += choiceButtons(labels, m.confirmIdx) + "\n"
+	return content + "\n" + m.help.View(confirmRemoteKeys)
+}
+
+// maxUnmergedShown bounds the commits listed before a forced delete.
+const maxUnmergedShown = 15
+
+func (m Model) confirmForceView() string {
+	base := m.base
+	if base == "" {
+		base = "HEAD"
+	}
+	content := styles.Title.Render("Branch Not Merged") + "\n\n"
+	content += styles.Selected.Render(m.editing.Name) + " is not fully merged.\n"
+	if len(m.unmerged) == 0 {
+		content += styles.Dimmed.Render("Its commits are all on "+base+", but not on its upstream or HEAD.") + "\n"
+	} else {
+		content += fmt.Sprintf("Deleting it will lose %d commit(s) not on %s:\n\n",
+			len(m.unmerged), styles.Remote.Render(base))
+		for _, c := range m.unmerged[:min(len(m.unmerged), maxUnmergedShown)] {
+			hash, subject, _ := strings.Cut(c, " ")
+			content += "  " + styles.Subtitle.Render(hash) + " " + subject + "\n"
+		}
+		if n := len(m.unmerged) - maxUnmergedShown; n > 0 {
+			content += styles.Dimmed.Render(fmt.Sprintf("  … and %d more", n)) + "\n"
+		}
+	}
+	return content + "\n" + m.help.View(confirmForceKeys)
 }
 
 func (m Model) View() tea.View {
@@ -513,13 +878,34 @@ func (m Model) View() tea.View {
 			"  " + styles.Subtitle.Render(elapsed)
 
 	case stateBrowse:
-		content = styles.Title.Render("Git Branch Manager") + "\n\n"
+		view := "local"
+		if m.showRemote {
+			view = "remote (+ no local branch)"
+		}
+		content = styles.Title.Render("Git Branch Manager") +
+			styles.Dimmed.Render("  "+view+" · sorted by "+m.sort.String()) + "\n\n"
 
-		if len(m.branches) == 0 {
+		rows := m.visible()
+		if m.filterShown() {
+			content += m.filter.View() + styles.Dimmed.Render(
+				fmt.Sprintf("  %d/%d", len(rows), len(m.list()))) + "\n"
+		}
+
+		switch {
+		case len(m.list()) == 0:
 			content += styles.Dimmed.Render("No branches found.")
-		} else {
-			var listContent strings.Builder
-			for i, b := range m.branches {
+		case len(rows) == 0:
+			content += styles.Dimmed.Render("No branches match.")
+		default:
+			cols := m.columns()
+
+			now := time.Now()
+			var lines []string
+			for i, b := range rows {
+				if m.showRemote && (i == 0 || rows[i-1].Remote != b.Remote) {
+					lines = append(lines, styles.Subtitle.Render(b.Remote))
+				}
+
 				cursor := "  "
 				nameStyle := lipgloss.NewStyle()
 
@@ -532,99 +918,82 @@ func (m Model) View() tea.View {
 				}
 
 				prefix := "  "
-				if b.IsCurrent {
+				switch {
+				case b.IsCurrent:
 					prefix = styles.CurrentBranch.Render("* ")
+				case b.Remote != "" && b.Local == "":
+					prefix = styles.Success.Render("+ ")
 				}
 
-				deleteMarker := ""
+				marker := ""
 				if m.deleteStaged && m.deleteStagedIdx == i {
-					deleteMarker = "  " + styles.Err.Render("d again to delete")
+					marker = "d again to delete"
 					nameStyle = styles.Err
 					if i == m.cursor {
 						cursor = styles.Err.Render("> ")
 					}
 				}
 
-				remote := ""
-				if b.HasRemote && deleteMarker == "" {
-					remote = "  " + styles.Remote.Render("["+b.Upstream+"]")
-				}
-
-				listContent.WriteString(fmt.Sprintf("%s%s%s%s%s",
-					cursor,
-					prefix,
-					nameStyle.Render(fmt.Sprintf("%-40s", b.Name)),
-					remote,
-					deleteMarker,
-				))
-				if i < len(m.branches)-1 {
-					listContent.WriteByte('\n')
-				}
+				lines = append(lines, cursor+prefix+cols.row(b.Branch, b.positions, nameStyle, marker, now))
 			}
 
-			m.viewport.SetContent(listContent.String())
-			content += m.viewport.View()
+			m.viewport.SetContent(strings.Join(lines, "\n"))
+			list := styles.Dimmed.Render(cols.header()) + "\n" + m.viewport.View()
+			if m.previewShown() {
+				list = lipgloss.JoinHorizontal(lipgloss.Top, list, m.previewView(m.viewport.Height()+1))
+			}
+			content += list
 
-			if len(m.branches) > m.viewport.Height() {
+			if len(lines) > m.viewport.Height() {
 				content += "\n" + styles.Dimmed.Render(
 					fmt.Sprintf("(%d%% — ↑↓/jk to scroll)", int(m.viewport.ScrollPercent()*100)),
 				)
 			}
 		}
 
-		content += "\n" + m.help.View(browseKeys)
+		switch {
+		case m.filtering:
+			content += "\n" + m.help.View(filterKeys)
+		case m.showRemote:
+			content += "\n" + m.help.View(remoteKeys)
+		default:
+			content += "\n" + m.help.View(browseKeys)
+		}
 
 	case stateEdit:
 		content = styles.Title.Render("Rename Branch") + "\n\n"
 		content += styles.Dimmed.Render("Old: ") + styles.Subtitle.Render(m.editing.Name) + "\n"
-		content += styles.Dimmed.Render("New: ") + m.input.View() + "\n"
+		content += styles.Dimmed.Render("New: ") + m.input.View() + "\n\n"
+		content += m.nameStatus() + "\n"
 		content += "\n" + m.help.View(editKeys)
 
 	case stateCreate:
-		newName := m.input.Value()
-		content = styles.Title.Render("New Branch") + "\n\n"
-		content += styles.Dimmed.Render("Name: ") + m.input.View() + "\n\n"
-
-		switch {
-		case newName == "":
-			content += styles.Dimmed.Render("enter a branch name")
-		case m.branchExists(newName):
-			content += styles.Err.Render("✗ branch already exists")
-		default:
-			content += styles.Success.Render("✓ name available")
-		}
-
-		content += "\n\n" + m.help.View(createKeys)
+		content = m.createView()
 
 	case stateConfirmRemote:
-		newName := strings.TrimSpace(m.input.Value())
-		remote, _ := splitUpstream(m.editing.Upstream)
-		newUpstream := remote + "/" + newName
+		content = m.confirmRemoteView()
 
-		content = styles.Title.Render("Update Remote?") + "\n\n"
-		content += fmt.Sprintf("Also rename %s\n         → %s?\n\n",
-			styles.Remote.Render(m.editing.Upstream),
-			styles.Selected.Render(newUpstream),
-		)
-
-		yesStyle := styles.Dimmed
-		noStyle := styles.Dimmed
-		if m.confirmIdx == 0 {
-			yesStyle = styles.Selected
-		} else {
-			noStyle = styles.Selected
-		}
-		content += fmt.Sprintf("  %s    %s\n",
-			yesStyle.Render("[ Yes ]"),
-			noStyle.Render("[ No ]"),
-		)
-		content += "\n" + m.help.View(confirmRemoteKeys)
+	case statePickRemote:
+		content = m.pickRemoteView()
 
 	case stateProcessing:
 		elapsed := fmt.Sprintf("%.2fs", m.stopwatch.Elapsed().Seconds())
 		content = m.spinner.View() + " " + styles.Dimmed.Render(m.processingMsg) +
 			"  " + styles.Subtitle.Render(elapsed)
 
+	case stateCleanup:
+		content = m.cleanupView()
+
+	case stateConfirmForce:
+		if len(m.cleanupUnmerged) > 0 {
+			content = m.forceCleanupView()
+		} else {
+			content = m.confirmForceView()
+		}
+
+	case stateDirtyCheckout:
+		content = m.dirtyView()
+
 	case stateResult:
 		content = styles.Title.Render("Done") + "\n\n"
 		content += m.result + "\n"
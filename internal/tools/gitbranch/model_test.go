@@ -1,6 +1,7 @@
 package gitbranch
 
 import (
+	"reflect"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
@@ -20,9 +21,20 @@ func modelWithBranches(branches []Branch) Model {
 	return m
 }
 
+// submitForm presses enter on a name form, delivering git's check on the
+// name if the submit waits for it.
+func submitForm(m Model) (Model, tea.Cmd) {
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	if m = r.(Model); !m.submitPending {
+		return m, cmd
+	}
+	r, cmd = m.Update(cmd())
+	return r.(Model), cmd
+}
+
 var testBranches = []Branch{
-	{Name: "main", Upstream: "origin/main", IsCurrent: true, HasRemote: true},
-	{Name: "feature/foo", Upstream: "origin/feature/foo", IsCurrent: false, HasRemote: true},
+	{Name: "main", Upstream: "origin/main", UpstreamRemote: "origin", UpstreamBranch: "main", IsCurrent: true, HasRemote: true},
+	{Name: "feature/foo", Upstream: "origin/feature/foo", UpstreamRemote: "origin", UpstreamBranch: "feature/foo", IsCurrent: false, HasRemote: true},
 	{Name: "local-only", Upstream: "", IsCurrent: false, HasRemote: false},
 }
 
@@ -30,27 +42,6 @@ var testBranches = []Branch{
 // Pure functions
 // ---------------------------------------------------------------------------
 
-func TestSplitUpstream(t *testing.T) {
-	tests := []struct {
-		input      string
-		wantRemote string
-		wantBranch string
-	}{
-		{"origin/main", "origin", "main"},
-		{"origin/feature/foo", "origin", "feature/foo"},
-		{"upstream/deep/nested/branch", "upstream", "deep/nested/branch"},
-		{"noSlash", "noSlash", "noSlash"},
-		{"", "", ""},
-	}
-	for _, tt := range tests {
-		remote, branch := splitUpstream(tt.input)
-		if remote != tt.wantRemote || branch != tt.wantBranch {
-			t.Errorf("splitUpstream(%q) = (%q, %q), want (%q, %q)",
-				tt.input, remote, branch, tt.wantRemote, tt.wantBranch)
-		}
-	}
-}
-
 func TestBranchExists(t *testing.T) {
 	m := modelWithBranches(testBranches)
 
@@ -227,8 +218,7 @@ func TestEdit_EnterWithRemote_GoesToConfirm(t *testing.T) {
 	m.editing = testBranches[1] // HasRemote=true
 	m.input.SetValue("feature/bar")
 
-	r, _ := m.Update(keyCode(tea.KeyEnter))
-	got := r.(Model)
+	got, _ := submitForm(m)
 
 	if got.state != stateConfirmRemote {
 		t.Errorf("expected stateConfirmRemote for branch with remote, got %d", got.state)
@@ -241,8 +231,7 @@ func TestEdit_EnterWithoutRemote_StartsRename(t *testing.T) {
 	m.editing = testBranches[2] // local-only, HasRemote=false
 	m.input.SetValue("new-local")
 
-	r, cmd := m.Update(keyCode(tea.KeyEnter))
-	got := r.(Model)
+	got, cmd := submitForm(m)
 
 	if got.state != stateProcessing {
 		t.Errorf("expected stateProcessing, got %d", got.state)
@@ -307,8 +296,7 @@ func TestCreate_EnterWithNewName_StartsCreate(t *testing.T) {
 	m.state = stateCreate
 	m.input.SetValue("feature/new")
 
-	r, cmd := m.Update(keyCode(tea.KeyEnter))
-	got := r.(Model)
+	got, cmd := submitForm(m)
 
 	if got.state != stateProcessing {
 		t.Errorf("expected stateProcessing, got %d", got.state)
@@ -420,6 +408,7 @@ func TestDelete_DifferentCursorClearsAndRestages(t *testing.T) {
 func TestConfirmRemote_Navigation(t *testing.T) {
 	m := modelWithBranches(testBranches)
 	m.state = stateConfirmRemote
+	m.editing = testBranches[1]
 	m.confirmIdx = 0
 
 	r, _ := m.Update(keyRune('l'))
@@ -447,8 +436,8 @@ func TestConfirmRemote_YShortcut(t *testing.T) {
 	if got.state != stateProcessing {
 		t.Errorf("expected stateProcessing, got %d", got.state)
 	}
-	if !got.didRemote {
-		t.Error("expected didRemote=true")
+	if got.renameRemote != "origin" {
+		t.Errorf("expected the rename on origin, got %q", got.renameRemote)
 	}
 	if cmd == nil {
 		t.Error("expected non-nil cmd")
@@ -467,14 +456,32 @@ func TestConfirmRemote_NShortcut(t *testing.T) {
 	if got.state != stateProcessing {
 		t.Errorf("expected stateProcessing, got %d", got.state)
 	}
-	if got.didRemote {
-		t.Error("expected didRemote=false")
+	if got.renameRemote != "" {
+		t.Errorf("expected a local-only rename, got %q", got.renameRemote)
 	}
 	if cmd == nil {
 		t.Error("expected non-nil cmd")
 	}
 }
 
+func TestConfirmRemote_PicksAnotherRemote(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.state = stateConfirmRemote
+	m.editing = testBranches[1]
+	m.remoteNames = []string{"upstream", "origin"}
+	m.input.SetValue("feature/bar")
+
+	if got := m.renameChoices(); !reflect.DeepEqual(got, []string{"origin", "upstream", "No"}) {
+		t.Fatalf("choices = %v", got)
+	}
+	m = pressAll(m, keyRune('l'))
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	got := r.(Model)
+	if got.state != stateProcessing || cmd == nil || got.renameRemote != "upstream" {
+		t.Errorf("expected a rename on upstream, got state %d on %q", got.state, got.renameRemote)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Async result messages
 // ---------------------------------------------------------------------------
@@ -522,6 +529,36 @@ func TestDeleteResultMsg_Success(t *testing.T) {
 	}
 }
 
+func TestDeleteResultMsg_NotMerged_ConfirmsForce(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.editing = testBranches[1]
+
+	r, _ := m.Update(deleteResultMsg{err: errNotMerged, commits: []string{"abc1234 wip"}})
+	got := r.(Model)
+	if got.state != stateConfirmForce {
+		t.Fatalf("expected stateConfirmForce, got %d", got.state)
+	}
+	if got.errSplash != "" {
+		t.Error("a refused delete should not show the error splash")
+	}
+
+	// Anything but D keeps the branch.
+	r, cmd := got.Update(keyRune('d'))
+	if r.(Model).state != stateConfirmForce || cmd != nil {
+		t.Error("d should not force the delete")
+	}
+	r, _ = got.Update(keyCode(tea.KeyEscape))
+	if r.(Model).state != stateBrowse {
+		t.Error("esc should return to browse")
+	}
+
+	r, cmd = got.Update(keyRune('D'))
+	got = r.(Model)
+	if got.state != stateProcessing || cmd == nil {
+		t.Errorf("D should start a forced delete, got state %d", got.state)
+	}
+}
+
 func TestDeleteResultMsg_Error(t *testing.T) {
 	m := modelWithBranches(testBranches)
 	m.state = stateProcessing
@@ -572,7 +609,7 @@ func TestRenameResultMsg_PartialSuccess(t *testing.T) {
 	m.state = stateProcessing
 	m.editing = testBranches[1] // feature/foo with origin/feature/foo upstream
 	m.input.SetValue("feature/bar")
-	m.didRemote = true
+	m.renameRemote = "origin"
 
 	r, _ := m.Update(renameResultMsg{localOk: true, err: errForTest("push failed")})
 	got := r.(Model)
@@ -609,7 +646,7 @@ func TestRenameResultMsg_FullSuccess(t *testing.T) {
 	m.state = stateProcessing
 	m.editing = testBranches[1]
 	m.input.SetValue("feature/bar")
-	m.didRemote = true
+	m.renameRemote = "origin"
 
 	r, _ := m.Update(renameResultMsg{localOk: true, remoteOk: true, err: nil})
 	got := r.(Model)
@@ -0,0 +1,216 @@
+package gitbranch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// namingRules are the branch naming conventions from git config:
+//
+//	rig.branchPrefix    a prefix new names must start with; may be repeated
+//	rig.branchPattern   a regular expression new names must match
+//	rig.branchTemplate  a name template offered on create; may be repeated
+//	rig.user            the {user} placeholder, defaulting to user.name
+type namingRules struct {
+	prefixes  []string
+	pattern   *regexp.Regexp
+	templates []string
+	user      string
+}
+
+// loadNaming reads the naming rules. Having none configured is not an
+// error. An invalid rule is skipped and reported while the rest still
+// load.
+func loadNaming() (namingRules, error) {
+	var r namingRules
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "config", "--get-regexp", `^rig\.|^user\.name$`)
+	cmd.Stderr = &buf
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return r, nil // no matching keys
+	}
+	if err != nil {
+		return r, fmt.Errorf("read naming rules: %s", strings.TrimSpace(buf.String()))
+	}
+
+	var userName string
+	var ruleErr error
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Keys are printed lower-cased.
+		k, v, _ := strings.Cut(line, " ")
+		switch k {
+		case "rig.branchprefix":
+			r.prefixes = append(r.prefixes, v)
+		case "rig.branchpattern":
+			if r.pattern, err = regexp.Compile(v); err != nil {
+				ruleErr = fmt.Errorf("rig.branchPattern: %w", err)
+			}
+		case "rig.branchtemplate":
+			r.templates = append(r.templates, v)
+		case "rig.user":
+			r.user = v
+		case "user.name":
+			userName = v
+		}
+	}
+	if r.user == "" {
+		r.user = slugify(userName)
+	}
+	return r, ruleErr
+}
+
+// check reports how name breaks the rules, if it does.
+func (r namingRules) check(name string) error {
+	hasPrefix := func(p string) bool { return strings.HasPrefix(name, p) }
+	if len(r.prefixes) > 0 && !slices.ContainsFunc(r.prefixes, hasPrefix) {
+		return fmt.Errorf("must start with %s", strings.Join(r.prefixes, ", "))
+	}
+	if r.pattern != nil && !r.pattern.MatchString(name) {
+		return fmt.Errorf("must match %s", r.pattern)
+	}
+	return nil
+}
+
+// expand fills a template's {user}, {ticket} and {title} placeholders. The
+// title is slugified; the ticket is used as typed.
+func (r namingRules) expand(template, ticket, title string) string {
+	return strings.NewReplacer(
+		"{user}", r.user,
+		"{ticket}", strings.TrimSpace(ticket),
+		"{title}", slugify(title),
+	).Replace(template)
+}
+
+// slugify lower-cases s and joins its words with hyphens, dropping
+// anything that is not a letter or digit.
+func slugify(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// checkRefFormat asks git whether name is a valid branch name.
+func checkRefFormat(name string) error {
+	if exec.Command("git", "check-ref-format", "--branch", name).Run() != nil {
+		return errors.New("not a valid branch name")
+	}
+	return nil
+}
+
+// nameCheckMsg is git's verdict on a name typed into the create or rename
+// form. It is ignored if the name has changed since.
+type nameCheckMsg struct {
+	name string
+	err  error
+}
+
+func cmdCheckName(name string) tea.Cmd {
+	return func() tea.Msg {
+		return nameCheckMsg{name: name, err: checkRefFormat(name)}
+	}
+}
+
+// updateName passes a key to the name input, checking the name with git
+// whenever it changes.
+func (m Model) updateName(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	prev := m.input.Value()
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() == prev {
+		return m, cmd
+	}
+	m.submitPending = false
+	return m, tea.Batch(cmd, m.nameChanged())
+}
+
+// nameChanged starts checking the name input's new value.
+func (m Model) nameChanged() tea.Cmd {
+	name := strings.TrimSpace(m.input.Value())
+	if name == "" {
+		return nil
+	}
+	return cmdCheckName(name)
+}
+
+// awaitNameCheck reports whether git's check on the typed name is still
+// pending. If so the form is submitted once the verdict arrives, so it is
+// never submitted unchecked.
+func (m Model) awaitNameCheck() (Model, tea.Cmd, bool) {
+	name := strings.TrimSpace(m.input.Value())
+	if name == "" || m.nameCheck.name == name {
+		return m, nil, false
+	}
+	m.submitPending = true
+	return m, cmdCheckName(name), true
+}
+
+// submitName submits the create or rename form.
+func (m Model) submitName() (tea.Model, tea.Cmd) {
+	switch m.state {
+	case stateCreate:
+		return m.submitCreate()
+	case stateEdit:
+		return m.submitRename()
+	}
+	return m, nil
+}
+
+// nameProblem reports why the name typed in the create or rename form
+// cannot be used, or "" if it can. git's check is skipped while it is
+// still running for this name.
+func (m Model) nameProblem() string {
+	name := strings.TrimSpace(m.input.Value())
+	switch {
+	case name == "":
+		return "enter a branch name"
+	case m.state == stateEdit && name == m.editing.Name:
+		return ""
+	case m.branchExists(name):
+		return "branch already exists"
+	case m.nameCheck.name == name && m.nameCheck.err != nil:
+		return m.nameCheck.err.Error()
+	}
+	if err := m.naming.check(name); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// nameStatus renders the verdict on the typed name, followed by any error
+// in the naming config.
+func (m Model) nameStatus() string {
+	status := m.nameVerdict()
+	if m.namingErr != nil {
+		status += "\n" + styles.Err.Render("✗ naming rules: "+m.namingErr.Error())
+	}
+	return status
+}
+
+func (m Model) nameVerdict() string {
+	name := strings.TrimSpace(m.input.Value())
+	switch problem := m.nameProblem(); {
+	case name == "":
+		return styles.Dimmed.Render(problem)
+	case problem != "":
+		return styles.Err.Render("✗ " + problem)
+	case m.state == stateEdit && name == m.editing.Name:
+		return styles.Dimmed.Render("unchanged")
+	case m.nameCheck.name != name:
+		return styles.Dimmed.Render("checking…")
+	default:
+		return styles.Success.Render("✓ name available")
+	}
+}
@@ -0,0 +1,231 @@
+package gitbranch
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Fix the login page":     "fix-the-login-page",
+		"  Über  café!! ":        "über-café",
+		"PROJ-12: add (retries)": "proj-12-add-retries",
+		"":                       "",
+	}
+	for in, want := range tests {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNamingRules_Expand(t *testing.T) {
+	r := namingRules{user: "ryan"}
+	got := r.expand("{user}/{ticket}-{title}", " PROJ-12 ", "Add retry logic")
+	if want := "ryan/PROJ-12-add-retry-logic"; got != want {
+		t.Errorf("expand = %q, want %q", got, want)
+	}
+}
+
+func TestNamingRules_Check(t *testing.T) {
+	r := namingRules{
+		prefixes: []string{"feat/", "fix/"},
+		pattern:  regexp.MustCompile(`/[A-Z]+-\d+`),
+	}
+	tests := map[string]string{
+		"feat/PROJ-1-thing": "",
+		"chore/PROJ-1":      "must start with feat/, fix/",
+		"fix/no-ticket":     `must match /[A-Z]+-\d+`,
+	}
+	for name, want := range tests {
+		got := ""
+		if err := r.check(name); err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Errorf("check(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if err := (namingRules{}).check("anything"); err != nil {
+		t.Errorf("no rules should accept anything, got %v", err)
+	}
+}
+
+func TestLoadNaming(t *testing.T) {
+	gitRepo(t)
+	git := gittest.Git(t)
+	git("config", "user.name", "Ryan Rushton")
+	git("config", "--add", "rig.branchPrefix", "feat/")
+	git("config", "--add", "rig.branchPrefix", "fix/")
+	git("config", "rig.branchPattern", `^\w+/[A-Z]+-\d+`)
+	git("config", "rig.branchTemplate", "feat/{ticket}-{title}")
+
+	r, err := loadNaming()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.prefixes, []string{"feat/", "fix/"}) || r.pattern == nil ||
+		!reflect.DeepEqual(r.templates, []string{"feat/{ticket}-{title}"}) {
+		t.Errorf("unexpected rules %+v", r)
+	}
+	if r.user != "ryan-rushton" {
+		t.Errorf("user = %q, want the slugified user.name", r.user)
+	}
+
+	git("config", "rig.user", "ryan")
+	if r, _ = loadNaming(); r.user != "ryan" {
+		t.Errorf("rig.user should override user.name, got %q", r.user)
+	}
+
+	git("config", "rig.branchPattern", "(")
+	r, err = loadNaming()
+	if err == nil || !strings.Contains(err.Error(), "rig.branchPattern") {
+		t.Errorf("expected an error naming the bad pattern, got %v", err)
+	}
+	if len(r.prefixes) != 2 || r.pattern != nil {
+		t.Errorf("the other rules should still load, got %+v", r)
+	}
+
+	msg := fetchBranches().(branchesLoadedMsg)
+	if msg.err != nil || len(msg.branches) == 0 || msg.namingErr == nil {
+		t.Fatalf("branches should load despite the bad pattern, got %+v", msg)
+	}
+	m := New()
+	res, _ := m.Update(msg)
+	m = pressAll(res.(Model), keyRune('e'))
+	if !strings.Contains(m.nameStatus(), "rig.branchPattern") {
+		t.Errorf("status = %q, want the config error", m.nameStatus())
+	}
+}
+
+func TestCheckRefFormat(t *testing.T) {
+	for _, name := range []string{"feat/ok", "fix/PROJ-1_thing"} {
+		if err := checkRefFormat(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"a..b", "has space", "x.lock", "trailing/", "-dash"} {
+		if checkRefFormat(name) == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestEdit_BlocksInvalidNames(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "local-only"}})
+	m.naming = namingRules{prefixes: []string{"feat/"}}
+	m = pressAll(m, keyRune('e'))
+
+	m.input.SetValue("chore/x")
+	if r, cmd := m.Update(keyCode(tea.KeyEnter)); r.(Model).state != stateEdit || cmd != nil {
+		t.Error("a name breaking the rules should not be submitted")
+	}
+
+	m.input.SetValue("feat/a..b")
+	r, _ := m.Update(nameCheckMsg{name: "feat/a..b", err: checkRefFormat("feat/a..b")})
+	m = r.(Model)
+	if !strings.Contains(m.nameStatus(), "not a valid branch name") {
+		t.Errorf("status = %q", m.nameStatus())
+	}
+	if r, cmd := m.Update(keyCode(tea.KeyEnter)); r.(Model).state != stateEdit || cmd != nil {
+		t.Error("a name git rejects should not be submitted")
+	}
+
+	// A verdict on an older name is not applied to the current one.
+	m.input.SetValue("feat/ok")
+	if m.nameProblem() != "" {
+		t.Errorf("stale check applied: %q", m.nameProblem())
+	}
+	if got, _ := submitForm(m); got.state != stateProcessing {
+		t.Error("a valid name should be submitted")
+	}
+}
+
+func TestEdit_SubmitWaitsForNameCheck(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "local-only"}})
+	m = pressAll(m, keyRune('e'))
+	m.input.SetValue("a..b") // typed, but git's verdict has not arrived
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateEdit || !m.submitPending || cmd == nil {
+		t.Fatalf("submit should wait for the check, state %d", m.state)
+	}
+	r, cmd = m.Update(cmd())
+	if m = r.(Model); m.state != stateEdit || cmd != nil || !strings.Contains(m.nameStatus(), "not a valid branch name") {
+		t.Errorf("an invalid name should not be submitted, state %d", m.state)
+	}
+
+	m.input.SetValue("ok")
+	r, cmd = m.Update(keyCode(tea.KeyEnter))
+	r, _ = r.(Model).Update(cmd())
+	if r.(Model).state != stateProcessing {
+		t.Error("a valid name should be submitted once checked")
+	}
+}
+
+func TestNameCheck_IgnoresOlderNames(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "local-only"}})
+	m = pressAll(m, keyRune('e'))
+	m.input.SetValue("feat/a..b")
+	r, _ := m.Update(nameCheckMsg{name: "feat/a..b", err: checkRefFormat("feat/a..b")})
+	m = r.(Model)
+
+	// The check for a name typed earlier arrives late.
+	r, _ = m.Update(nameCheckMsg{name: "feat/a"})
+	if !strings.Contains(r.(Model).nameStatus(), "not a valid branch name") {
+		t.Errorf("a late verdict replaced the current one: %q", r.(Model).nameStatus())
+	}
+}
+
+func TestEdit_TypingChecksName(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "local-only"}})
+	m = pressAll(m, keyRune('e'))
+	if !strings.Contains(m.nameStatus(), "unchanged") {
+		t.Errorf("status = %q", m.nameStatus())
+	}
+	_, cmd := m.Update(keyRune('!'))
+	if cmd == nil {
+		t.Fatal("typing should check the name")
+	}
+}
+
+func TestCreate_Template(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.naming = namingRules{user: "ryan", templates: []string{"{user}/{title}", "fix/{ticket}-{title}"}}
+	m.openCreate()
+	if m.createField != fieldTemplate || m.template() != "{user}/{title}" {
+		t.Fatalf("the first template should be chosen and focused, got %q on %d", m.template(), m.createField)
+	}
+
+	m = pressAll(m, keyCode(tea.KeyRight), keyCode(tea.KeyTab))
+	if m.createField != fieldTicket {
+		t.Fatalf("expected the ticket field, on %d", m.createField)
+	}
+	m = typeText(m, "PROJ-7")
+	m = pressAll(m, keyCode(tea.KeyTab))
+	m = typeText(m, "Retry uploads")
+	if got := m.input.Value(); got != "fix/PROJ-7-retry-uploads" {
+		t.Errorf("name = %q", got)
+	}
+
+	m = pressAll(m, keyCode(tea.KeyTab))
+	if m.createField != fieldName {
+		t.Fatalf("expected the name field, on %d", m.createField)
+	}
+	m = typeText(m, "-2")
+	if got := m.input.Value(); got != "fix/PROJ-7-retry-uploads-2" {
+		t.Errorf("the expanded name should stay editable, got %q", got)
+	}
+
+	// Choosing no template hides its fields and keeps the name.
+	m = pressAll(m, keyCode(tea.KeyUp), keyCode(tea.KeyUp), keyCode(tea.KeyUp), keyCode(tea.KeyRight))
+	if m.template() != "" || m.createFieldShown(fieldTicket) || m.input.Value() != "fix/PROJ-7-retry-uploads-2" {
+		t.Errorf("template %q, name %q", m.template(), m.input.Value())
+	}
+}
@@ -0,0 +1,183 @@
+package gitbranch
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+const (
+	// previewMinWidth is the narrowest window that shows the preview pane.
+	previewMinWidth = 120
+	maxPreviewWidth = 70
+	previewRecent   = 8 // commits in the recent log
+	previewSide     = 5 // commits listed ahead of and behind the base
+)
+
+// preview is what the pane shows for a branch. It is cached per ref until
+// the branches are reloaded.
+type preview struct {
+	loading       bool
+	recent        []string
+	ahead, behind []string
+	files         []fileStat
+	err           error
+}
+
+type previewLoadedMsg struct {
+	ref     string
+	preview preview
+}
+
+// previewRef is the full ref of b, unambiguous between local and
+// remote-tracking branches.
+func previewRef(b Branch) string {
+	if b.Remote != "" {
+		return "refs/remotes/" + b.Name
+	}
+	return "refs/heads/" + b.Name
+}
+
+// cmdPreview loads the preview of ref. Comparisons with base are skipped
+// when there is no default branch.
+func cmdPreview(base, ref string) tea.Cmd {
+	return func() tea.Msg {
+		var p preview
+		if p.recent, p.err = logOneline("-n", fmt.Sprint(previewRecent), ref); p.err != nil || base == "" {
+			return previewLoadedMsg{ref: ref, preview: p}
+		}
+		if p.ahead, p.err = logOneline("-n", fmt.Sprint(previewSide), base+".."+ref); p.err == nil {
+			if p.behind, p.err = logOneline("-n", fmt.Sprint(previewSide), ref+".."+base); p.err == nil {
+				p.files, p.err = diffStat(base, ref)
+			}
+		}
+		return previewLoadedMsg{ref: ref, preview: p}
+	}
+}
+
+// previewShown reports whether the window is wide enough for the pane.
+func (m Model) previewShown() bool {
+	return m.width >= previewMinWidth
+}
+
+// previewWidth is the width the pane takes from the list, including the gap
+// and border, or 0 when it is not shown.
+func (m Model) previewWidth() int {
+	if !m.previewShown() {
+		return 0
+	}
+	// border(2) + padding(2) horizontal on each side, as for the list.
+	return min((m.width-6)*2/5, maxPreviewWidth) + 4
+}
+
+// withPreview starts loading the preview of the branch under the cursor if
+// it is not cached. It wraps Update results so every cursor move is covered.
+func withPreview(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || m.state != stateBrowse || !m.previewShown() {
+		return model, cmd
+	}
+	b, ok := m.selected()
+	if !ok {
+		return m, cmd
+	}
+	ref := previewRef(b)
+	if _, cached := m.previews[ref]; cached {
+		return m, cmd
+	}
+	previews := maps.Clone(m.previews)
+	if previews == nil {
+		previews = make(map[string]preview)
+	}
+	previews[ref] = preview{loading: true}
+	m.previews = previews
+	return m, tea.Batch(cmd, cmdPreview(m.base, ref))
+}
+
+// previewView renders the pane for the branch under the cursor, height
+// lines tall.
+func (m Model) previewView(height int) string {
+	width := m.previewWidth() - 4
+	style := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(styles.DimGray).
+		PaddingLeft(1).
+		MarginLeft(1).
+		Width(width + 2).
+		Height(height)
+
+	b, ok := m.selected()
+	if !ok {
+		return style.Render("")
+	}
+	p := m.previews[previewRef(b)]
+
+	lines := []string{styles.Selected.Render(b.Name), ""}
+	switch {
+	case p.loading:
+		lines = append(lines, styles.Dimmed.Render("loading…"))
+	case p.err != nil:
+		lines = append(lines, styles.Err.Render(p.err.Error()))
+	default:
+		lines = append(lines, styles.Dimmed.Render("Recent commits"))
+		lines = append(lines, commitLines(p.recent, len(p.recent))...)
+		if m.base != "" {
+			lines = append(lines, "", styles.Dimmed.Render(fmt.Sprintf("Ahead of %s (%d)", m.base, b.BaseAhead)))
+			lines = append(lines, commitLines(p.ahead, b.BaseAhead)...)
+			lines = append(lines, "", styles.Dimmed.Render(fmt.Sprintf("Behind %s (%d)", m.base, b.BaseBehind)))
+			lines = append(lines, commitLines(p.behind, b.BaseBehind)...)
+			lines = append(lines, "")
+			lines = append(lines, fileLines(p.files, width)...)
+		}
+	}
+
+	for i, l := range lines {
+		lines[i] = ansi.Truncate(l, width, "…")
+	}
+	return style.Render(strings.Join(lines[:min(len(lines), height)], "\n"))
+}
+
+// commitLines renders one-line commits, noting how many of total were left
+// out.
+func commitLines(commits []string, total int) []string {
+	lines := make([]string, 0, len(commits)+1)
+	for _, c := range commits {
+		hash, subject, _ := strings.Cut(c, " ")
+		lines = append(lines, "  "+styles.Subtitle.Render(hash)+" "+subject)
+	}
+	if n := total - len(commits); n > 0 {
+		lines = append(lines, styles.Dimmed.Render(fmt.Sprintf("  … and %d more", n)))
+	}
+	return lines
+}
+
+// fileLines renders a diffstat in width cells: a total, then each file with
+// its counts.
+func fileLines(files []fileStat, width int) []string {
+	if len(files) == 0 {
+		return []string{styles.Dimmed.Render("No changes since the merge base")}
+	}
+	added, deleted := 0, 0
+	for _, f := range files {
+		added += f.added
+		deleted += f.deleted
+	}
+	lines := []string{styles.Dimmed.Render(fmt.Sprintf("Changed since merge base: %d files ", len(files))) +
+		styles.Success.Render(fmt.Sprintf("+%d", added)) + " " + styles.Err.Render(fmt.Sprintf("-%d", deleted))}
+
+	const countsWidth = 12
+	for _, f := range files {
+		counts := styles.Dimmed.Render("binary")
+		if !f.binary {
+			counts = styles.Success.Render(fmt.Sprintf("+%d", f.added)) + " " + styles.Err.Render(fmt.Sprintf("-%d", f.deleted))
+		}
+		lines = append(lines, "  "+cell(f.path, max(width-countsWidth-3, 10))+" "+counts)
+	}
+	return lines
+}
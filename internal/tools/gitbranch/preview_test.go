@@ -0,0 +1,102 @@
+package gitbranch
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestCmdPreview(t *testing.T) {
+	gitRepo(t)
+	git := gittest.Git(t)
+	git("switch", "-q", "topic")
+	if err := os.WriteFile("notes.txt", []byte("a\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "notes.txt")
+	git("commit", "-q", "-m", "add notes")
+
+	msg := cmdPreview("main", "refs/heads/topic")().(previewLoadedMsg)
+	p := msg.preview
+	if msg.ref != "refs/heads/topic" || p.err != nil {
+		t.Fatalf("preview = %+v", msg)
+	}
+	subjects := func(commits []string) []string {
+		var s []string
+		for _, c := range commits {
+			_, subject, _ := strings.Cut(c, " ")
+			s = append(s, subject)
+		}
+		return s
+	}
+	if got := subjects(p.recent); !reflect.DeepEqual(got, []string{"add notes", "topic work", "root"}) {
+		t.Errorf("recent = %q", got)
+	}
+	if got := subjects(p.ahead); !reflect.DeepEqual(got, []string{"add notes", "topic work"}) {
+		t.Errorf("ahead = %q", got)
+	}
+	if got := subjects(p.behind); !reflect.DeepEqual(got, []string{"main work"}) {
+		t.Errorf("behind = %q", got)
+	}
+	if want := []fileStat{{path: "notes.txt", added: 2}}; !reflect.DeepEqual(p.files, want) {
+		t.Errorf("files = %+v, want %+v", p.files, want)
+	}
+
+	if p := cmdPreview("", "refs/heads/topic")().(previewLoadedMsg).preview; p.ahead != nil || p.files != nil || len(p.recent) != 3 {
+		t.Errorf("without a base only the log should load, got %+v", p)
+	}
+}
+
+func TestPreview_LoadsOnceWhenWide(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "main"}, {Name: "topic"}})
+
+	r, cmd := m.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	m = r.(Model)
+	if cmd != nil || m.previews != nil {
+		t.Fatal("a narrow window should not load a preview")
+	}
+
+	r, cmd = m.Update(tea.WindowSizeMsg{Width: 150, Height: 30})
+	m = r.(Model)
+	if cmd == nil || !m.previews["refs/heads/main"].loading {
+		t.Fatal("a wide window should load the selected branch's preview")
+	}
+	if m.viewport.Width() != 150-6-m.previewWidth() {
+		t.Errorf("list width = %d, should leave room for the pane", m.viewport.Width())
+	}
+
+	r, _ = m.Update(previewLoadedMsg{ref: "refs/heads/main", preview: preview{recent: []string{"abc1234 hello there"}}})
+	m = r.(Model)
+	if !strings.Contains(ansi.Strip(m.View().Content), "abc1234 hello there") {
+		t.Error("the loaded preview should be shown")
+	}
+
+	r, cmd = m.Update(keyRune('j'))
+	m = r.(Model)
+	if cmd == nil || !m.previews["refs/heads/topic"].loading {
+		t.Error("moving the cursor should load the next preview")
+	}
+	if _, cmd = m.Update(keyRune('k')); cmd != nil {
+		t.Error("a cached preview should not be loaded again")
+	}
+
+	r, _ = m.Update(branchesLoadedMsg{branches: m.branches})
+	if p := r.(Model).previews; len(p) != 1 || !p["refs/heads/topic"].loading {
+		t.Errorf("a reload should drop the cache and reload the selected preview, got %v", p)
+	}
+}
+
+func TestPreviewRef(t *testing.T) {
+	if got := previewRef(Branch{Name: "origin/main", Remote: "origin"}); got != "refs/remotes/origin/main" {
+		t.Errorf("remote ref = %q", got)
+	}
+	if got := previewRef(Branch{Name: "main"}); got != "refs/heads/main" {
+		t.Errorf("local ref = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package gitbranch
+
+import "strings"
+
+// list returns the branches the browse view is showing: local branches, or
+// remote-tracking branches when toggled.
+func (m Model) list() []Branch {
+	if m.showRemote {
+		return m.remotes
+	}
+	return m.branches
+}
+
+// localName is the name of the local branch checking out remote-tracking
+// branch b creates: its name without the remote.
+func localName(b Branch) string {
+	if b.Local != "" {
+		return b.Local
+	}
+	return strings.TrimPrefix(b.Name, b.Remote+"/")
+}
+
+// cursorLine is the viewport line of the cursor's row, which is further
+// down than the row index by the remote headers above it.
+func (m Model) cursorLine() int {
+	if !m.showRemote {
+		return m.cursor
+	}
+	line := m.cursor
+	rows := m.visible()
+	for i := 0; i <= m.cursor && i < len(rows); i++ {
+		if i == 0 || rows[i-1].Remote != rows[i].Remote {
+			line++
+		}
+	}
+	return line
+}
+
+// scrollToCursor keeps the cursor's row in view, along with the header of
+// the first group.
+func (m *Model) scrollToCursor() {
+	if m.cursor == 0 {
+		m.viewport.SetYOffset(0)
+		return
+	}
+	ensureCursorVisible(&m.viewport, m.cursorLine())
+}
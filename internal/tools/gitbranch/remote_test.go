@@ -0,0 +1,154 @@
+package gitbranch
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+var testRemotes = []Branch{
+	{Name: "origin/main", Remote: "origin", Local: "main"},
+	{Name: "upstream/colleague", Remote: "upstream"},
+	{Name: "origin/feature/foo", Remote: "origin", Local: "feature/foo"},
+	{Name: "origin/colleague", Remote: "origin"},
+}
+
+func TestRemoteOf(t *testing.T) {
+	remotes := []string{"origin", "team", "team/alice"}
+	tests := map[string]string{
+		"origin/main":          "origin",
+		"team/alice/feature":   "team/alice",
+		"team/bob":             "team",
+		"elsewhere/branch":     "",
+		"origin":               "origin",
+		"originals/not-origin": "",
+	}
+	for name, want := range tests {
+		if got := remoteOf(name, remotes); got != want {
+			t.Errorf("remoteOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLocalCounterpart(t *testing.T) {
+	local := []Branch{
+		{Name: "mine", Upstream: "origin/theirs"},
+		{Name: "topic"},
+	}
+	tests := map[string]string{
+		"origin/theirs": "mine",
+		"origin/topic":  "topic",
+		"origin/other":  "",
+	}
+	for name, want := range tests {
+		b := Branch{Name: name, Remote: "origin"}
+		if got := localCounterpart(b, local); got != want {
+			t.Errorf("localCounterpart(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGetRemoteBranches(t *testing.T) {
+	clonedRepo(t)
+
+	local, err := getBranches("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remotes, err := getRemoteBranches("origin/main", []string{"origin"}, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, b := range remotes {
+		names = append(names, b.Name+"→"+b.Local)
+	}
+	if want := []string{"origin/main→main", "origin/topic→"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("remote branches = %v, want %v", names, want)
+	}
+	if topic := remotes[1]; topic.Remote != "origin" || topic.BaseAhead != 1 || topic.Subject != "topic work" {
+		t.Errorf("unexpected remote branch %+v", topic)
+	}
+
+	if err := trackBranch("topic", "origin/topic"); err != nil {
+		t.Fatal(err)
+	}
+	local, _ = getBranches("")
+	remotes, _ = getRemoteBranches("", []string{"origin"}, local)
+	if remotes[1].Local != "topic" {
+		t.Errorf("origin/topic should be tracked by topic, got %q", remotes[1].Local)
+	}
+}
+
+func TestRemoteView_GroupsByRemote(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.remotes = testRemotes
+
+	r, _ := m.Update(keyCode(tea.KeyTab))
+	m = r.(Model)
+	if !m.showRemote {
+		t.Fatal("tab should show remote branches")
+	}
+	want := []string{"origin/main", "origin/feature/foo", "origin/colleague", "upstream/colleague"}
+	if got := visibleNames(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("visible = %v, want %v", got, want)
+	}
+
+	// One header above origin, a second above upstream.
+	m.cursor = 3
+	if got := m.cursorLine(); got != 5 {
+		t.Errorf("cursorLine = %d, want 5", got)
+	}
+
+	r, _ = m.Update(keyCode(tea.KeyTab))
+	m = r.(Model)
+	if m.showRemote || len(m.visible()) != len(testBranches) {
+		t.Error("tab should switch back to local branches")
+	}
+}
+
+func TestRemoteView_EnterTracksAndShowsLocal(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.remotes = testRemotes
+	m.showRemote = true
+	m.cursor = 2 // origin/colleague
+
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateProcessing || cmd == nil {
+		t.Fatalf("expected checkout to start, got state %d", m.state)
+	}
+	if m.editing.Name != "origin/colleague" {
+		t.Errorf("editing %q", m.editing.Name)
+	}
+
+	r, _ = m.Update(checkoutResultMsg{})
+	m = r.(Model)
+	if m.showRemote || m.focus != "colleague" {
+		t.Errorf("expected local view focused on colleague, got showRemote=%v focus=%q", m.showRemote, m.focus)
+	}
+
+	r, _ = m.Update(branchesLoadedMsg{branches: append(testBranches, Branch{Name: "colleague", IsCurrent: true})})
+	m = r.(Model)
+	if b, _ := m.selected(); b.Name != "colleague" {
+		t.Errorf("cursor on %q, want colleague", b.Name)
+	}
+}
+
+func TestRemoteView_RenameAndDeleteIgnored(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.remotes = testRemotes
+	m.showRemote = true
+
+	r, _ := m.Update(keyRune('e'))
+	m = r.(Model)
+	if m.state != stateBrowse {
+		t.Errorf("rename should be ignored on remote branches, got state %d", m.state)
+	}
+	r, _ = m.Update(keyRune('d'))
+	m = r.(Model)
+	if m.deleteStaged {
+		t.Error("delete should be ignored on remote branches")
+	}
+}
@@ -0,0 +1,48 @@
+package gitbranch
+
+import "sort"
+
+// sortMode is the order branches are listed in.
+type sortMode int
+
+const (
+	sortName sortMode = iota
+	sortRecent
+	sortDivergence
+	sortModeCount
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortRecent:
+		return "most recent"
+	case sortDivergence:
+		return "ahead/behind"
+	default:
+		return "name"
+	}
+}
+
+func (s sortMode) next() sortMode { return (s + 1) % sortModeCount }
+
+// sortBranches orders branches in place. Divergence puts the branches with
+// the most unmerged work first, and among those the least stale.
+func sortBranches(branches []Branch, mode sortMode) {
+	sort.SliceStable(branches, func(i, j int) bool {
+		a, b := branches[i], branches[j]
+		switch mode {
+		case sortRecent:
+			if !a.CommitDate.Equal(b.CommitDate) {
+				return a.CommitDate.After(b.CommitDate)
+			}
+		case sortDivergence:
+			if a.BaseAhead != b.BaseAhead {
+				return a.BaseAhead > b.BaseAhead
+			}
+			if a.BaseBehind != b.BaseBehind {
+				return a.BaseBehind < b.BaseBehind
+			}
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,86 @@
+package gitbranch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func branchNames(branches []Branch) []string {
+	var names []string
+	for _, b := range branches {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func TestSortBranches(t *testing.T) {
+	now := time.Now()
+	branches := []Branch{
+		{Name: "b", CommitDate: now.Add(-time.Hour), BaseAhead: 2, BaseBehind: 5},
+		{Name: "a", CommitDate: now.Add(-48 * time.Hour)},
+		{Name: "c", CommitDate: now, BaseAhead: 2, BaseBehind: 1},
+		{Name: "d", CommitDate: now.Add(-time.Minute), BaseAhead: 7},
+	}
+
+	tests := []struct {
+		mode sortMode
+		want []string
+	}{
+		{sortName, []string{"a", "b", "c", "d"}},
+		{sortRecent, []string{"c", "d", "b", "a"}},
+		{sortDivergence, []string{"d", "c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		sortBranches(branches, tt.mode)
+		if got := branchNames(branches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort by %s = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestSortMode_Cycles(t *testing.T) {
+	if got := sortDivergence.next(); got != sortName {
+		t.Errorf("next after %s = %s, want name", sortDivergence, got)
+	}
+}
+
+func TestBrowse_SortKeyKeepsCursorOnBranch(t *testing.T) {
+	now := time.Now()
+	m := modelWithBranches([]Branch{
+		{Name: "alpha", CommitDate: now.Add(-2 * time.Hour)},
+		{Name: "beta", CommitDate: now.Add(-time.Hour)},
+		{Name: "gamma", CommitDate: now},
+	})
+	m.cursor = 0
+
+	r, _ := m.Update(keyRune('s'))
+	m = r.(Model)
+	if m.sort != sortRecent {
+		t.Fatalf("sort = %s, want most recent", m.sort)
+	}
+	if got := branchNames(m.branches); !reflect.DeepEqual(got, []string{"gamma", "beta", "alpha"}) {
+		t.Errorf("branches = %v", got)
+	}
+	if m.branches[m.cursor].Name != "alpha" {
+		t.Errorf("cursor on %q, want alpha", m.branches[m.cursor].Name)
+	}
+}
+
+func TestBranchesLoadedMsg_AppliesSort(t *testing.T) {
+	now := time.Now()
+	m := New()
+	m.sort = sortRecent
+
+	r, _ := m.Update(branchesLoadedMsg{branches: []Branch{
+		{Name: "old", CommitDate: now.Add(-time.Hour)},
+		{Name: "new", CommitDate: now},
+	}, base: "origin/main"})
+	m = r.(Model)
+	if got := branchNames(m.branches); !reflect.DeepEqual(got, []string{"new", "old"}) {
+		t.Errorf("branches = %v, want [new old]", got)
+	}
+	if m.base != "origin/main" {
+		t.Errorf("base = %q", m.base)
+	}
+}
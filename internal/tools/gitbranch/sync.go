@@ -0,0 +1,175 @@
+package gitbranch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+var pickRemoteKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←→/hl", "select")),
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "push")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}}
+
+// syncResultMsg reports a push or fast-forward of name against upstream,
+// with the ahead/behind counts before and after. hadUpstream is false when
+// a push set the upstream.
+type syncResultMsg struct {
+	verb          string // past tense, e.g. "Pushed"
+	name          string
+	upstream      string
+	hadUpstream   bool
+	before, after [2]int // ahead, behind
+	err           error
+}
+
+// remoteChoices lists the remotes b can be pushed or renamed to, its
+// upstream's remote first.
+func remoteChoices(b Branch, remotes []string) []string {
+	var choices []string
+	if b.HasRemote {
+		choices = append(choices, b.UpstreamRemote)
+	}
+	for _, r := range remotes {
+		if !slices.Contains(choices, r) {
+			choices = append(choices, r)
+		}
+	}
+	return choices
+}
+
+// defaultPushChoice is the index in choices pushes start on: the upstream's
+// remote, otherwise origin, otherwise the first.
+func defaultPushChoice(b Branch, choices []string) int {
+	if b.HasRemote {
+		return 0
+	}
+	return max(0, slices.Index(choices, "origin"))
+}
+
+// pushTarget returns the branch b is pushed to on remote: its upstream
+// branch there, otherwise a branch of the same name.
+func pushTarget(b Branch, remote string) string {
+	if b.HasRemote && b.UpstreamRemote == remote {
+		return b.UpstreamBranch
+	}
+	return b.Name
+}
+
+// cmdPush pushes b to remote and tracks it there.
+func cmdPush(b Branch, remote string) tea.Cmd {
+	branch := pushTarget(b, remote)
+	return func() tea.Msg {
+		msg := syncResultMsg{
+			verb:        "Pushed",
+			name:        b.Name,
+			upstream:    remote + "/" + branch,
+			hadUpstream: b.HasRemote && b.UpstreamRemote == remote && !b.UpstreamGone,
+			before:      [2]int{b.Ahead, b.Behind},
+		}
+		if msg.err = pushBranch(remote, b.Name, branch); msg.err == nil {
+			msg.after = upstreamCounts(b.Name)
+		}
+		return msg
+	}
+}
+
+// cmdFastForward brings b up to date with its upstream: with git pull when
+// it is checked out, otherwise by fetching straight into it.
+func cmdFastForward(b Branch) tea.Cmd {
+	return func() tea.Msg {
+		msg := syncResultMsg{verb: "Fast-forwarded", name: b.Name, upstream: b.Upstream, hadUpstream: true, before: [2]int{b.Ahead, b.Behind}}
+		if !b.HasRemote || b.UpstreamGone {
+			msg.err = errors.New(b.Name + " has no upstream to fast-forward from")
+			return msg
+		}
+		if b.IsCurrent {
+			msg.verb = "Pulled"
+			msg.err = pullFastForward()
+		} else {
+			msg.err = fastForward(b.UpstreamRemote, b.UpstreamBranch, b.Name)
+		}
+		if msg.err == nil {
+			msg.after = upstreamCounts(b.Name)
+		}
+		return msg
+	}
+}
+
+// upstreamCounts returns how far the local branch name is ahead of and
+// behind its upstream.
+func upstreamCounts(name string) [2]int {
+	ahead, behind := aheadBehind(name+"@{upstream}", "refs/heads/"+name)
+	return [2]int{ahead, behind}
+}
+
+// startPush pushes b, first asking which remote when there is a choice.
+func (m Model) startPush(b Branch) (Model, tea.Cmd) {
+	choices := remoteChoices(b, m.remoteNames)
+	switch len(choices) {
+	case 0:
+		return showError(m, errors.New("no remote to push to")), nil
+	case 1:
+		return startAsync(m, stateProcessing, "Pushing "+b.Name+"...", cmdPush(b, choices[0]))
+	}
+	m.editing = b
+	m.confirmIdx = defaultPushChoice(b, choices)
+	m.state = statePickRemote
+	return m, nil
+}
+
+func (m Model) handlePickRemoteKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	choices := remoteChoices(m.editing, m.remoteNames)
+	switch msg.String() {
+	case "esc", "q":
+		m.state = stateBrowse
+	case "left", "h", "shift+tab":
+		m.confirmIdx = max(0, m.confirmIdx-1)
+	case "right", "l", "tab":
+		m.confirmIdx = min(len(choices)-1, m.confirmIdx+1)
+	case "enter", "space":
+		remote := choices[m.confirmIdx]
+		return startAsync(m, stateProcessing, "Pushing "+m.editing.Name+"...", cmdPush(m.editing, remote))
+	}
+	return m, nil
+}
+
+func (m Model) pickRemoteView() string {
+	choices := remoteChoices(m.editing, m.remoteNames)
+	content := styles.Title.Render("Push Branch") + "\n\n"
+	content += "Push " + styles.Selected.Render(m.editing.Name) + " to " +
+		styles.Remote.Render(choices[m.confirmIdx]+"/"+pushTarget(m.editing, choices[m.confirmIdx])) + "\n\n"
+	content += choiceButtons(choices, m.confirmIdx) + "\n"
+	return content + "\n" + m.help.View(pickRemoteKeys)
+}
+
+// choiceButtons renders labels as buttons, highlighting the selected one.
+func choiceButtons(labels []string, selected int) string {
+	buttons := make([]string, len(labels))
+	for i, l := range labels {
+		style := styles.Dimmed
+		if i == selected {
+			style = styles.Selected
+		}
+		buttons[i] = style.Render("[ " + l + " ]")
+	}
+	return "  " + strings.Join(buttons, "    ")
+}
+
+// syncSummary renders the result of a successful push or fast-forward.
+func syncSummary(msg syncResultMsg) string {
+	before := styles.Dimmed.Render("no upstream")
+	if msg.hadUpstream {
+		before = styles.Remote.Render(divergence(msg.before[0], msg.before[1], "↑", "↓"))
+	}
+	after := styles.Remote.Render(divergence(msg.after[0], msg.after[1], "↑", "↓"))
+	return styles.Success.Render("✓") + " " + msg.verb + " " + styles.Selected.Render(msg.name) +
+		" ⇄ " + styles.Remote.Render(msg.upstream) + "\n" +
+		styles.Dimmed.Render("  upstream: ") + before + styles.Dimmed.Render(" → ") + after
+}
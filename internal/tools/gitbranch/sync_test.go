@@ -0,0 +1,226 @@
+package gitbranch
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRemoteChoices(t *testing.T) {
+	fork := Branch{Name: "mine", Upstream: "fork/theirs", UpstreamRemote: "fork", UpstreamBranch: "theirs", HasRemote: true}
+	remotes := []string{"origin", "fork", "team/ada"}
+
+	choices := remoteChoices(fork, remotes)
+	if want := []string{"fork", "origin", "team/ada"}; !reflect.DeepEqual(choices, want) {
+		t.Errorf("choices = %v, want %v", choices, want)
+	}
+	if i := defaultPushChoice(fork, choices); i != 0 {
+		t.Errorf("push should default to the upstream's remote, got %d", i)
+	}
+	if got := pushTarget(fork, "fork"); got != "theirs" {
+		t.Errorf("push to the upstream's remote should use its branch, got %q", got)
+	}
+	if got := pushTarget(fork, "team/ada"); got != "mine" {
+		t.Errorf("push elsewhere should use the local name, got %q", got)
+	}
+
+	fresh := Branch{Name: "new"}
+	choices = remoteChoices(fresh, remotes)
+	if i := defaultPushChoice(fresh, choices); choices[i] != "origin" {
+		t.Errorf("push without an upstream should default to origin, got %q", choices[i])
+	}
+}
+
+func TestPush_PicksRemote(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "new"}})
+	if r, _ := m.Update(keyRune('p')); r.(Model).errSplash == "" {
+		t.Error("pushing without remotes should show an error")
+	}
+
+	m.remoteNames = []string{"origin"}
+	if r, cmd := m.Update(keyRune('p')); r.(Model).state != stateProcessing || cmd == nil {
+		t.Error("a single remote should be pushed to directly")
+	}
+
+	m.remoteNames = []string{"upstream", "origin"}
+	m = pressAll(m, keyRune('p'))
+	if m.state != statePickRemote || m.confirmIdx != 1 {
+		t.Fatalf("expected the remote picker on origin, got state %d at %d", m.state, m.confirmIdx)
+	}
+	if !strings.Contains(m.pickRemoteView(), "origin/new") {
+		t.Error("picker should show where the branch goes")
+	}
+	m = pressAll(m, keyRune('h'))
+	if !strings.Contains(m.pickRemoteView(), "upstream/new") {
+		t.Error("picker should follow the selection")
+	}
+	if r, cmd := m.Update(keyCode(tea.KeyEnter)); r.(Model).state != stateProcessing || cmd == nil {
+		t.Error("enter should push")
+	}
+}
+
+func branchNamed(t *testing.T, name string) Branch {
+	t.Helper()
+	branches, err := getBranches("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range branches {
+		if b.Name == name {
+			return b
+		}
+	}
+	t.Fatalf("no branch %s", name)
+	return Branch{}
+}
+
+func TestCmdPush_SetsUpstream(t *testing.T) {
+	git, _ := clonedRepo(t)
+	git("switch", "-q", "-c", "feature")
+	git("commit", "-q", "--allow-empty", "-m", "feature work")
+
+	msg := cmdPush(branchNamed(t, "feature"), "origin")().(syncResultMsg)
+	if msg.err != nil || msg.hadUpstream || msg.upstream != "origin/feature" || msg.after != [2]int{} {
+		t.Fatalf("push = %+v", msg)
+	}
+	if b := branchNamed(t, "feature"); b.Upstream != "origin/feature" {
+		t.Errorf("upstream = %q, want origin/feature", b.Upstream)
+	}
+
+	git("commit", "-q", "--allow-empty", "-m", "more work")
+	msg = cmdPush(branchNamed(t, "feature"), "origin")().(syncResultMsg)
+	if msg.err != nil || msg.before != [2]int{1, 0} || msg.after != [2]int{} {
+		t.Errorf("second push = %+v", msg)
+	}
+	if !strings.Contains(syncSummary(msg), "↑1") {
+		t.Errorf("summary should show the counts before, got %q", syncSummary(msg))
+	}
+}
+
+func TestCmdFastForward(t *testing.T) {
+	git, origin := clonedRepo(t)
+	git("switch", "-q", "topic") // creates topic tracking origin/topic
+	git("switch", "-q", "main")
+	git("-C", origin, "commit", "-q", "--allow-empty", "-m", "newer main work")
+	git("-C", origin, "switch", "-q", "topic")
+	git("-C", origin, "commit", "-q", "--allow-empty", "-m", "newer topic work")
+	git("fetch", "-q")
+
+	topic := branchNamed(t, "topic")
+	msg := cmdFastForward(topic)().(syncResultMsg)
+	if msg.err != nil || msg.verb != "Fast-forwarded" || msg.before != [2]int{0, 1} || msg.after != [2]int{} {
+		t.Fatalf("fast-forward = %+v", msg)
+	}
+	if b := branchNamed(t, "topic"); b.Subject != "newer topic work" {
+		t.Errorf("topic is at %q", b.Subject)
+	}
+
+	msg = cmdFastForward(branchNamed(t, "main"))().(syncResultMsg)
+	if msg.err != nil || msg.verb != "Pulled" || msg.after != [2]int{} {
+		t.Fatalf("pull = %+v", msg)
+	}
+	if b := branchNamed(t, "main"); b.Subject != "newer main work" {
+		t.Errorf("main is at %q", b.Subject)
+	}
+
+	// Local work stays ahead of the upstream after a pull.
+	git("commit", "-q", "--allow-empty", "-m", "unpushed work")
+	msg = cmdFastForward(branchNamed(t, "main"))().(syncResultMsg)
+	if msg.err != nil || msg.before != [2]int{1, 0} || msg.after != [2]int{1, 0} {
+		t.Fatalf("pull when ahead = %+v", msg)
+	}
+
+	git("switch", "-q", "-c", "local-only")
+	if msg := cmdFastForward(branchNamed(t, "local-only"))().(syncResultMsg); msg.err == nil {
+		t.Error("expected an error without an upstream")
+	}
+}
+
+func TestSyncResultMsg(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "main"}})
+	r, _ := m.Update(syncResultMsg{verb: "Pushed", name: "main", upstream: "origin/main", hadUpstream: true, before: [2]int{2, 0}})
+	got := r.(Model)
+	if got.state != stateResult || got.focus != "main" || !strings.Contains(got.result, "Pushed") {
+		t.Errorf("state %d, result %q", got.state, got.result)
+	}
+}
+
+func TestUpstream_RemoteWithSlash(t *testing.T) {
+	git, origin := clonedRepo(t)
+	git("remote", "add", "team/ada", origin)
+	git("fetch", "-q", "team/ada")
+	git("switch", "-q", "-c", "mine", "--track", "team/ada/topic")
+	git("switch", "-q", "main")
+	git("-C", origin, "switch", "-q", "topic")
+	git("-C", origin, "commit", "-q", "--allow-empty", "-m", "newer topic work")
+	git("-C", origin, "switch", "-q", "main") // so topic can be pushed to
+
+	mine := branchNamed(t, "mine")
+	if mine.UpstreamRemote != "team/ada" || mine.UpstreamBranch != "topic" || !mine.HasRemote {
+		t.Fatalf("upstream = %q on %q", mine.UpstreamBranch, mine.UpstreamRemote)
+	}
+	if msg := cmdFastForward(mine)().(syncResultMsg); msg.err != nil {
+		t.Fatalf("fast-forward: %v", msg.err)
+	}
+	if b := branchNamed(t, "mine"); b.Subject != "newer topic work" {
+		t.Errorf("mine is at %q", b.Subject)
+	}
+
+	git("switch", "-q", "mine")
+	git("commit", "-q", "--allow-empty", "-m", "my work")
+	git("switch", "-q", "main")
+	msg := cmdPush(branchNamed(t, "mine"), "team/ada")().(syncResultMsg)
+	if msg.err != nil || msg.upstream != "team/ada/topic" || msg.after != [2]int{} {
+		t.Fatalf("push = %+v", msg)
+	}
+	out, err := exec.Command("git", "-C", origin, "log", "-1", "--format=%s", "topic").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "my work" {
+		t.Errorf("origin topic is at %q (%v)", out, err)
+	}
+}
+
+func TestRenameRemoteBranch_KeepsOldOnAnotherRemote(t *testing.T) {
+	git, origin := clonedRepo(t)
+	fork := t.TempDir()
+	git("init", "-q", "--bare", fork)
+	git("remote", "add", "fork", fork)
+	git("switch", "-q", "topic")
+	git("branch", "-m", "topic", "renamed")
+	git("switch", "-q", "main")
+
+	if err := renameRemoteBranch("origin", "topic", "fork", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	hasBranch := func(repo, name string) bool {
+		return exec.Command("git", "-C", repo, "rev-parse", "-q", "--verify", "refs/heads/"+name).Run() == nil
+	}
+	if !hasBranch(origin, "topic") {
+		t.Error("topic should be kept on origin")
+	}
+	if !hasBranch(fork, "renamed") {
+		t.Error("renamed should be pushed to fork")
+	}
+	if b := branchNamed(t, "renamed"); b.Upstream != "fork/renamed" {
+		t.Errorf("upstream = %q, want fork/renamed", b.Upstream)
+	}
+}
+
+func TestConfirmRemoteView_SaysOldUpstreamIsKept(t *testing.T) {
+	m := modelWithBranches([]Branch{{Name: "feature/foo", Upstream: "origin/feature/foo", UpstreamRemote: "origin", UpstreamBranch: "feature/foo", HasRemote: true}})
+	m.state = stateConfirmRemote
+	m.editing = m.branches[0]
+	m.remoteNames = []string{"origin", "upstream"}
+	m.input.SetValue("feature/bar")
+
+	if view := ansi.Strip(m.confirmRemoteView()); !strings.Contains(view, "Also rename") || strings.Contains(view, "is kept") {
+		t.Errorf("same remote view = %q", view)
+	}
+	m.confirmIdx = 1
+	if view := ansi.Strip(m.confirmRemoteView()); !strings.Contains(view, "upstream/feature/bar") || !strings.Contains(view, "origin/feature/foo is kept") {
+		t.Errorf("other remote view = %q", view)
+	}
+}
@@ -0,0 +1,135 @@
+package gitbranch
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+type undoKind int
+
+const (
+	undoDelete undoKind = iota
+	undoRename
+	undoCheckout
+)
+
+// deletedBranch is enough to recreate a deleted local branch.
+type deletedBranch struct {
+	name     string
+	sha      string
+	upstream string
+}
+
+// undoOp is a branch operation that can be reversed. Only local state is
+// restored: remote renames and deletes are not undone.
+type undoOp struct {
+	kind     undoKind
+	deleted  []deletedBranch // undoDelete
+	oldName  string          // undoRename
+	newName  string          // undoRename
+	prevHead string          // undoCheckout: branch name, or SHA if detached
+	detached bool            // undoCheckout
+}
+
+// String describes the operation being undone.
+func (op undoOp) String() string {
+	switch op.kind {
+	case undoDelete:
+		names := make([]string, len(op.deleted))
+		for i, d := range op.deleted {
+			names[i] = d.name
+		}
+		return "delete of " + strings.Join(names, ", ")
+	case undoRename:
+		return "rename of " + op.oldName + " → " + op.newName
+	default:
+		if op.detached {
+			return "checkout (back to " + shortSHA(op.prevHead) + ")"
+		}
+		return "checkout (back to " + op.prevHead + ")"
+	}
+}
+
+type undoResultMsg struct {
+	op        undoOp
+	untracked map[string]bool // restored branches whose upstream could not be set
+	err       error
+}
+
+// pushUndo records an operation. The stack is never shared with older
+// copies of the model once it has been popped from.
+func (m *Model) pushUndo(op undoOp) {
+	m.undo = append(m.undo[:len(m.undo):len(m.undo)], op)
+}
+
+// cmdUndo reverses op. Deleted branches are all restored even if one fails.
+func cmdUndo(op undoOp) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		untracked := make(map[string]bool)
+		switch op.kind {
+		case undoDelete:
+			var failed []string
+			for _, d := range op.deleted {
+				tracking, e := restoreBranch(d.name, d.sha, d.upstream)
+				switch {
+				case e != nil:
+					failed = append(failed, e.Error())
+				case d.upstream != "" && !tracking:
+					untracked[d.name] = true
+				}
+			}
+			if len(failed) > 0 {
+				err = fmt.Errorf("%s", strings.Join(failed, "\n"))
+			}
+		case undoRename:
+			err = renameBranch(op.newName, op.oldName)
+		case undoCheckout:
+			if op.detached {
+				err = detachAt(op.prevHead)
+			} else {
+				err = checkoutBranch(op.prevHead)
+			}
+		}
+		return undoResultMsg{op: op, untracked: untracked, err: err}
+	}
+}
+
+// undoSummary renders the result of a successful undo. untracked names the
+// restored branches whose upstream could not be set.
+func undoSummary(op undoOp, untracked map[string]bool) string {
+	check := styles.Success.Render("✓")
+	switch op.kind {
+	case undoDelete:
+		lines := make([]string, len(op.deleted))
+		for i, d := range op.deleted {
+			lines[i] = check + " Restored " + styles.Selected.Render(d.name) +
+				" at " + styles.Subtitle.Render(shortSHA(d.sha))
+			switch {
+			case d.upstream == "":
+			case untracked[d.name]:
+				lines[i] += styles.Dimmed.Render(" (could not track " + d.upstream + ")")
+			default:
+				lines[i] += " tracking " + styles.Remote.Render(d.upstream)
+			}
+		}
+		return strings.Join(lines, "\n")
+	case undoRename:
+		return check + " Renamed " + styles.Dimmed.Render(op.newName) + " back to " +
+			styles.Selected.Render(op.oldName)
+	default:
+		head := op.prevHead
+		if op.detached {
+			head = shortSHA(head)
+		}
+		return check + " Switched back to " + styles.Selected.Render(head)
+	}
+}
+
+func shortSHA(sha string) string {
+	return sha[:min(len(sha), 7)]
+}
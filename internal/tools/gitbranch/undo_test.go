@@ -0,0 +1,126 @@
+package gitbranch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestUndo_RestoresDeletedBranch(t *testing.T) {
+	gitRepo(t)
+	sha, err := branchSHA("topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New()
+	m.editing = Branch{Name: "topic"}
+	msg := m.cmdDelete("topic", true)().(deleteResultMsg)
+	if msg.err != nil || msg.undo == nil {
+		t.Fatalf("delete = %+v", msg)
+	}
+	if _, err := branchSHA("topic"); err == nil {
+		t.Fatal("topic should be deleted")
+	}
+
+	if res := cmdUndo(*msg.undo)().(undoResultMsg); res.err != nil {
+		t.Fatal(res.err)
+	}
+	if got, err := branchSHA("topic"); err != nil || got != sha {
+		t.Errorf("restored topic at %q (%v), want %q", got, err, sha)
+	}
+}
+
+func TestUndo_RenameAndCheckout(t *testing.T) {
+	gitRepo(t)
+
+	if err := renameBranch("topic", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if res := cmdUndo(undoOp{kind: undoRename, oldName: "topic", newName: "renamed"})().(undoResultMsg); res.err != nil {
+		t.Fatal(res.err)
+	}
+	if _, err := branchSHA("topic"); err != nil {
+		t.Error("rename should be reversed")
+	}
+
+	msg := cmdSwitch(Branch{Name: "topic"}, switchPlain)().(checkoutResultMsg)
+	if msg.err != nil || msg.undo == nil || msg.undo.prevHead != "main" || msg.undo.detached {
+		t.Fatalf("checkout = %+v", msg)
+	}
+	if res := cmdUndo(*msg.undo)().(undoResultMsg); res.err != nil {
+		t.Fatal(res.err)
+	}
+	if head, _, _ := currentHead(); head != "main" {
+		t.Errorf("HEAD = %q after undo, want main", head)
+	}
+}
+
+func TestUndoKey_PopsOnlyOnSuccess(t *testing.T) {
+	m := modelWithBranches(testBranches)
+
+	r, cmd := m.Update(keyRune('u'))
+	if r.(Model).state != stateBrowse || cmd != nil {
+		t.Fatal("u with nothing to undo should do nothing")
+	}
+
+	r, _ = m.Update(deleteResultMsg{undo: &undoOp{kind: undoDelete, deleted: []deletedBranch{{name: "gone", sha: "abc1234def"}}}})
+	m = r.(Model)
+	m.state = stateBrowse
+	r, _ = m.Update(renameResultMsg{localOk: true})
+	m = r.(Model)
+	m.state = stateBrowse
+	if len(m.undo) != 2 {
+		t.Fatalf("undo stack has %d ops, want 2", len(m.undo))
+	}
+
+	r, cmd = m.Update(keyRune('u'))
+	m = r.(Model)
+	if m.state != stateProcessing || cmd == nil {
+		t.Fatalf("u should start an undo, got state %d", m.state)
+	}
+
+	r, _ = m.Update(undoResultMsg{op: m.undo[1], err: errForTest("exists")})
+	m = r.(Model)
+	if m.errSplash == "" || len(m.undo) != 2 {
+		t.Errorf("a failed undo should keep the op, stack %d", len(m.undo))
+	}
+
+	r, _ = m.Update(undoResultMsg{op: m.undo[1]})
+	m = r.(Model)
+	if m.state != stateResult || len(m.undo) != 1 || m.undo[0].kind != undoDelete {
+		t.Errorf("a successful undo should pop the rename, got state %d stack %+v", m.state, m.undo)
+	}
+}
+
+func TestUndo_RestoreWithoutUpstream(t *testing.T) {
+	gitRepo(t)
+	sha, err := branchSHA("topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteBranch("topic", true); err != nil {
+		t.Fatal(err)
+	}
+
+	op := undoOp{kind: undoDelete, deleted: []deletedBranch{{name: "topic", sha: sha, upstream: "origin/topic"}}}
+	res := cmdUndo(op)().(undoResultMsg)
+	if res.err != nil || !res.untracked["topic"] {
+		t.Fatalf("undo = %+v", res)
+	}
+	summary := ansi.Strip(undoSummary(res.op, res.untracked))
+	if strings.Contains(summary, "tracking") || !strings.Contains(summary, "could not track origin/topic") {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestRenameResult_UndoUsesTrimmedName(t *testing.T) {
+	m := modelWithBranches(testBranches)
+	m.editing = Branch{Name: "topic"}
+	m.input.SetValue("  renamed ")
+	r, _ := m.Update(renameResultMsg{localOk: true})
+	if got := r.(Model).undo; len(got) != 1 || got[0].newName != "renamed" {
+		t.Errorf("undo = %+v", got)
+	}
+}
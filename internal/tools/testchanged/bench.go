@@ -0,0 +1,370 @@
+package testchanged
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// benchAlpha is the significance level below which a delta is reported.
+const benchAlpha = 0.05
+
+type benchDoneMsg struct {
+	rows  []benchRow
+	notes []string
+	err   error
+}
+
+// benchKey identifies one metric (e.g. ns/op) of one benchmark.
+type benchKey struct {
+	pkg  string
+	name string
+	unit string
+}
+
+// benchRow compares one metric between the merge base and the working tree.
+type benchRow struct {
+	benchKey
+	old []float64
+	new []float64
+	p   float64
+}
+
+// RunBenchmarks runs only benchmarks (no tests) for the given targets.
+func (GoRunner) RunBenchmarks(targets []string, count int) *exec.Cmd {
+	args := append([]string{"test", "-run", "^$", "-bench", ".", "-benchmem",
+		"-count", strconv.Itoa(count)}, targets...)
+	return exec.Command("go", args...)
+}
+
+// runBenchmarks benchmarks targets in the working tree and in a temporary
+// worktree at base, then compares the two sets of samples.
+func runBenchmarks(targets []string, base string, count int) tea.Cmd {
+	return func() tea.Msg {
+		out, err := GoRunner{}.RunBenchmarks(targets, count).CombinedOutput()
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		newSamples, order := parseBenchOutput(lines)
+		if err != nil && len(order) == 0 {
+			return benchDoneMsg{err: fmt.Errorf("benchmark working tree: %s", tail(lines, 10))}
+		}
+
+		prefix, err := exec.Command("git", "rev-parse", "--show-prefix").Output()
+		if err != nil {
+			return benchDoneMsg{err: fmt.Errorf("resolve repo prefix: %w", err)}
+		}
+		dir, err := addWorktree(base)
+		if err != nil {
+			return benchDoneMsg{err: err}
+		}
+		defer removeWorktree(dir)
+
+		// Targets are relative to the directory rig was started in.
+		cmd := GoRunner{}.RunBenchmarks(targets, count)
+		cmd.Dir = filepath.Join(dir, strings.TrimSpace(string(prefix)))
+		out, err = cmd.CombinedOutput()
+		oldSamples, oldOrder := parseBenchOutput(strings.Split(string(out), "\n"))
+
+		var notes []string
+		if err != nil {
+			notes = append(notes, "merge base run failed; benchmarks missing there have no baseline")
+		}
+
+		for _, k := range oldOrder {
+			if _, ok := newSamples[k]; !ok {
+				order = append(order, k)
+			}
+		}
+		if len(order) == 0 {
+			return benchDoneMsg{err: fmt.Errorf("no benchmarks found in selected targets")}
+		}
+
+		// Group rows by package, then by unit, keeping run order otherwise.
+		pkgIdx := make(map[string]int)
+		unitIdx := make(map[string]int)
+		rows := make([]benchRow, 0, len(order))
+		for _, k := range order {
+			if _, ok := pkgIdx[k.pkg]; !ok {
+				pkgIdx[k.pkg] = len(pkgIdx)
+			}
+			if _, ok := unitIdx[k.unit]; !ok {
+				unitIdx[k.unit] = len(unitIdx)
+			}
+			r := benchRow{benchKey: k, old: oldSamples[k], new: newSamples[k]}
+			r.p = mannWhitneyP(r.old, r.new)
+			rows = append(rows, r)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].pkg != rows[j].pkg {
+				return pkgIdx[rows[i].pkg] < pkgIdx[rows[j].pkg]
+			}
+			return unitIdx[rows[i].unit] < unitIdx[rows[j].unit]
+		})
+		return benchDoneMsg{rows: rows, notes: notes}
+	}
+}
+
+// parseBenchOutput collects samples from go test -bench output. Keys are
+// returned in first-seen order so the table follows the run order.
+func parseBenchOutput(lines []string) (map[benchKey][]float64, []benchKey) {
+	samples := make(map[benchKey][]float64)
+	var order []benchKey
+	pkg := ""
+	for _, line := range lines {
+		if p, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(p)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			k := benchKey{pkg: pkg, name: fields[0], unit: fields[i+1]}
+			if _, ok := samples[k]; !ok {
+				order = append(order, k)
+			}
+			samples[k] = append(samples[k], v)
+		}
+	}
+	return samples, order
+}
+
+// mannWhitneyP returns the two-sided p-value of a Mann-Whitney U test
+// comparing x and y. Small samples without ties use the exact distribution;
+// otherwise a tie-corrected normal approximation is used.
+func mannWhitneyP(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type obs struct {
+		v     float64
+		fromX bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range y {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Assign 1-based ranks, averaging across runs of equal values.
+	var rankX, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankX += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+	u := rankX - float64(n1*(n1+1))/2
+
+	if tieTerm == 0 && n1+n2 <= 50 {
+		dist := uDistribution(n1, n2)
+		var below, above, total float64
+		for v, c := range dist {
+			total += c
+			if float64(v) <= u {
+				below += c
+			}
+			if float64(v) >= u {
+				above += c
+			}
+		}
+		return math.Min(1, 2*math.Min(below, above)/total)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(0, (math.Abs(u-mean)-0.5)/sigma)
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// uDistribution returns, for each possible U, the number of orderings of
+// n1 and n2 distinct samples that produce it.
+func uDistribution(n1, n2 int) []float64 {
+	// f[i][j] is the distribution for i samples from x and j from y. The
+	// largest sample either comes from x (beating all j from y) or from y.
+	f := make([][][]float64, n1+1)
+	for i := range f {
+		f[i] = make([][]float64, n2+1)
+		for j := range f[i] {
+			f[i][j] = make([]float64, i*j+1)
+			if i == 0 || j == 0 {
+				f[i][j][0] = 1
+				continue
+			}
+			for u := range f[i][j] {
+				if u-j >= 0 && u-j < len(f[i-1][j]) {
+					f[i][j][u] += f[i-1][j][u-j]
+				}
+				if u < len(f[i][j-1]) {
+					f[i][j][u] += f[i][j-1][u]
+				}
+			}
+		}
+	}
+	return f[n1][n2]
+}
+
+// meanSpread returns the mean of xs and the largest deviation from it as a
+// percentage of the mean.
+func meanSpread(xs []float64) (mean, spread float64) {
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	if mean == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		spread = math.Max(spread, math.Abs(x-mean)/mean*100)
+	}
+	return mean, spread
+}
+
+// formatBenchValue scales a value into a readable unit, e.g. 1523 ns/op → 1.523µs.
+func formatBenchValue(unit string, v float64) string {
+	scale := func(v float64, base float64, suffixes []string) string {
+		i := 0
+		for math.Abs(v) >= base && i < len(suffixes)-1 {
+			v /= base
+			i++
+		}
+		return strconv.FormatFloat(v, 'g', 4, 64) + suffixes[i]
+	}
+	switch unit {
+	case "ns/op":
+		return scale(v, 1000, []string{"ns", "µs", "ms", "s"})
+	case "B/op":
+		return scale(v, 1024, []string{"B", "KiB", "MiB", "GiB"})
+	default:
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+}
+
+// renderBenchTable formats rows as a benchstat-style table, grouped by package.
+func renderBenchTable(rows []benchRow, notes []string) []string {
+	type cells struct {
+		name, old, new, delta, stat string
+		deltaStyle                  func(...string) string
+	}
+
+	var table []cells
+	nameW, oldW, newW := len("name"), len("base"), len("working tree")
+	for _, r := range rows {
+		c := cells{name: strings.TrimPrefix(r.name, "Benchmark") + " " + r.unit, old: "-", new: "-", delta: "~"}
+		c.deltaStyle = styles.Dimmed.Render
+		if len(r.old) > 0 {
+			mean, spread := meanSpread(r.old)
+			c.old = fmt.Sprintf("%s ±%.0f%%", formatBenchValue(r.unit, mean), spread)
+		}
+		if len(r.new) > 0 {
+			mean, spread := meanSpread(r.new)
+			c.new = fmt.Sprintf("%s ±%.0f%%", formatBenchValue(r.unit, mean), spread)
+		}
+		switch {
+		case len(r.old) == 0 || len(r.new) == 0:
+			c.delta = "new"
+			if len(r.new) == 0 {
+				c.delta = "gone"
+			}
+		default:
+			oldMean, _ := meanSpread(r.old)
+			newMean, _ := meanSpread(r.new)
+			c.stat = fmt.Sprintf("(p=%.3f n=%d+%d)", r.p, len(r.old), len(r.new))
+			if r.p < benchAlpha && oldMean != 0 {
+				pct := (newMean - oldMean) / oldMean * 100
+				c.delta = fmt.Sprintf("%+.2f%%", pct)
+				worse := pct > 0
+				if strings.HasSuffix(r.unit, "/s") {
+					worse = pct < 0
+				}
+				if worse {
+					c.deltaStyle = styles.Err.Render
+				} else {
+					c.deltaStyle = styles.Success.Render
+				}
+			}
+		}
+		nameW = max(nameW, len([]rune(c.name)))
+		oldW = max(oldW, len([]rune(c.old)))
+		newW = max(newW, len([]rune(c.new)))
+		table = append(table, c)
+	}
+
+	pad := func(s string, w int) string {
+		return s + strings.Repeat(" ", max(0, w-len([]rune(s))))
+	}
+
+	var lines []string
+	for _, n := range notes {
+		lines = append(lines, styles.Err.Render(n), "")
+	}
+	header := pad("name", nameW) + "  " + pad("base", oldW) + "  " + pad("working tree", newW) + "  delta"
+	pkg := ""
+	for i, c := range table {
+		if i == 0 || rows[i].pkg != pkg {
+			pkg = rows[i].pkg
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			if pkg != "" {
+				lines = append(lines, styles.Subtitle.Render("pkg: "+pkg))
+			}
+			lines = append(lines, styles.Dimmed.Render(header))
+		}
+		lines = append(lines, pad(c.name, nameW)+"  "+pad(c.old, oldW)+"  "+pad(c.new, newW)+"  "+
+			c.deltaStyle(pad(c.delta, 8))+" "+styles.Dimmed.Render(c.stat))
+	}
+	return lines
+}
+
+// tail joins the last n non-empty lines, for compact error messages.
+func tail(lines []string, n int) string {
+	var kept []string
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept[max(0, len(kept)-n):], "\n")
+}
+
+// shortSHA abbreviates a commit hash for display.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,102 @@
+package testchanged
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestParseBenchOutput(t *testing.T) {
+	lines := []string{
+		"goos: linux",
+		"pkg: example.com/foo",
+		"BenchmarkAdd-8   \t 1000000\t      1200 ns/op\t      16 B/op\t       1 allocs/op",
+		"BenchmarkAdd-8   \t 1000000\t      1300 ns/op\t      16 B/op\t       1 allocs/op",
+		"PASS",
+		"pkg: example.com/bar",
+		"BenchmarkAdd-8   \t  500000\t      2000 ns/op",
+		"BenchmarkBroken-8 notanumber 12 ns/op",
+	}
+
+	samples, order := parseBenchOutput(lines)
+
+	if len(order) != 4 {
+		t.Fatalf("expected 4 keys, got %d: %v", len(order), order)
+	}
+	foo := benchKey{pkg: "example.com/foo", name: "BenchmarkAdd-8", unit: "ns/op"}
+	if got := samples[foo]; len(got) != 2 || got[0] != 1200 || got[1] != 1300 {
+		t.Errorf("unexpected samples for %v: %v", foo, got)
+	}
+	bar := benchKey{pkg: "example.com/bar", name: "BenchmarkAdd-8", unit: "ns/op"}
+	if got := samples[bar]; len(got) != 1 || got[0] != 2000 {
+		t.Errorf("unexpected samples for %v: %v", bar, got)
+	}
+}
+
+func TestMannWhitneyP(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// Completely separated samples of 3: P(U=0) = 1/20, two-sided = 0.1.
+		{"separated n=3", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},
+		{"separated n=5", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"identical", []float64{1, 1, 1}, []float64{1, 1, 1}, 1},
+		{"empty", nil, []float64{1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mannWhitneyP(tt.x, tt.y)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("mannWhitneyP = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBenchValue(t *testing.T) {
+	tests := []struct {
+		unit string
+		v    float64
+		want string
+	}{
+		{"ns/op", 950, "950ns"},
+		{"ns/op", 1523, "1.523µs"},
+		{"ns/op", 2500000, "2.5ms"},
+		{"B/op", 2048, "2KiB"},
+		{"allocs/op", 3, "3"},
+	}
+	for _, tt := range tests {
+		if got := formatBenchValue(tt.unit, tt.v); got != tt.want {
+			t.Errorf("formatBenchValue(%q, %v) = %q, want %q", tt.unit, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRunBenchmarks_ModuleInSubdir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := gittest.Git(t)
+	git("init", "-q")
+	if err := os.Mkdir("mod", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gittest.WriteFile(t, "mod/go.mod", "module example.com/m\n\ngo 1.24\n")
+	gittest.WriteFile(t, "mod/m_test.go", "package m\n\nimport \"testing\"\n\nfunc BenchmarkNop(b *testing.B) {\n\tfor range b.N {\n\t}\n}\n")
+	git("add", ".")
+	git("commit", "-qm", "bench")
+	t.Chdir("mod")
+
+	msg := runBenchmarks([]string{"./..."}, "HEAD", 1)().(benchDoneMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	if len(msg.notes) > 0 {
+		t.Errorf("notes = %v, want the merge base run to succeed", msg.notes)
+	}
+	if len(msg.rows) == 0 || len(msg.rows[0].old) != 1 || len(msg.rows[0].new) != 1 {
+		t.Errorf("rows = %+v, want one sample on each side", msg.rows)
+	}
+}
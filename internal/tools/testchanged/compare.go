@@ -0,0 +1,180 @@
+package testchanged
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// maxDurationChanges is how many duration changes the comparison shows.
+const maxDurationChanges = 3
+
+// minDurationChange hides timing noise from the comparison.
+const minDurationChange = 10 * time.Millisecond
+
+// outcomeKey identifies a test across runs. name is empty for packages or
+// Bazel targets that reported no per-test results.
+type outcomeKey struct {
+	pkg  string
+	name string
+}
+
+func (k outcomeKey) String() string {
+	if k.name == "" {
+		return k.pkg
+	}
+	if k.pkg == "" {
+		return k.name
+	}
+	return path.Base(k.pkg) + "." + k.name
+}
+
+// outcome is the result of one test in a run.
+type outcome struct {
+	failed   bool
+	skipped  bool
+	duration time.Duration
+}
+
+// durationChange is a test that got slower (delta > 0) or faster.
+type durationChange struct {
+	key      outcomeKey
+	old, new time.Duration
+}
+
+func (d durationChange) delta() time.Duration { return d.new - d.old }
+
+// runComparison summarises how a run differs from the previous run of the
+// same targets.
+type runComparison struct {
+	newlyFailing []outcomeKey
+	fixed        []outcomeKey
+	stillFailing []outcomeKey
+	changes      []durationChange // largest first
+}
+
+// collectOutcomes extracts per-test outcomes from analysed output. Packages
+// without per-test lines (build failures, Bazel targets) are recorded as a
+// single outcome.
+func collectOutcomes(lines []string, info []lineInfo) map[outcomeKey]outcome {
+	outcomes := make(map[outcomeKey]outcome)
+	hasTests := make(map[string]bool)
+	var pkgs []packageResult
+	for i, line := range lines {
+		if r, ok := parseTestResult(line); ok {
+			pkg := info[i].pkg
+			hasTests[pkg] = true
+			outcomes[outcomeKey{pkg: pkg, name: r.name}] = outcome{
+				failed:   r.status == "FAIL",
+				skipped:  r.status == "SKIP",
+				duration: r.duration,
+			}
+			continue
+		}
+		if r, ok := parsePackageResult(line); ok {
+			pkgs = append(pkgs, r)
+		}
+	}
+	for _, r := range pkgs {
+		if hasTests[r.pkg] {
+			continue
+		}
+		outcomes[outcomeKey{pkg: r.pkg}] = outcome{
+			failed:   !r.passed,
+			skipped:  r.skipped,
+			duration: r.duration,
+		}
+	}
+	return outcomes
+}
+
+// compareRuns diffs two sets of outcomes. Tests missing from either run are
+// only counted as newly failing; a test that disappeared isn't "fixed".
+func compareRuns(prev, cur map[outcomeKey]outcome) runComparison {
+	var c runComparison
+	for k, o := range cur {
+		p, seen := prev[k]
+		switch {
+		case o.failed && seen && p.failed:
+			c.stillFailing = append(c.stillFailing, k)
+		case o.failed:
+			c.newlyFailing = append(c.newlyFailing, k)
+		case seen && p.failed && !o.skipped:
+			c.fixed = append(c.fixed, k)
+		}
+		if seen && !o.skipped && !p.skipped && p.duration > 0 && o.duration > 0 {
+			d := durationChange{key: k, old: p.duration, new: o.duration}
+			if d.delta().Abs() >= minDurationChange {
+				c.changes = append(c.changes, d)
+			}
+		}
+	}
+
+	byName := func(keys []outcomeKey) {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+	byName(c.newlyFailing)
+	byName(c.fixed)
+	byName(c.stillFailing)
+	sort.Slice(c.changes, func(i, j int) bool {
+		di, dj := c.changes[i].delta().Abs(), c.changes[j].delta().Abs()
+		if di != dj {
+			return di > dj
+		}
+		return c.changes[i].key.String() < c.changes[j].key.String()
+	})
+	if len(c.changes) > maxDurationChanges {
+		c.changes = c.changes[:maxDurationChanges]
+	}
+	return c
+}
+
+// render returns the comparison as header lines for the results view.
+func (c runComparison) render() []string {
+	summary := []string{
+		styles.Err.Render(fmt.Sprintf("%d newly failing", len(c.newlyFailing))),
+		styles.Success.Render(fmt.Sprintf("%d fixed", len(c.fixed))),
+		styles.Dimmed.Render(fmt.Sprintf("%d still failing", len(c.stillFailing))),
+	}
+	lines := []string{styles.Subtitle.Render("vs previous run: ") + strings.Join(summary, styles.Dimmed.Render(", "))}
+
+	if len(c.newlyFailing) > 0 {
+		lines = append(lines, styles.Dimmed.Render("newly failing: ")+
+			styles.Err.Render(joinKeys(c.newlyFailing, 4)))
+	}
+	if len(c.fixed) > 0 {
+		lines = append(lines, styles.Dimmed.Render("fixed: ")+
+			styles.Success.Render(joinKeys(c.fixed, 4)))
+	}
+	if len(c.changes) > 0 {
+		var parts []string
+		for _, d := range c.changes {
+			sign := "+"
+			style := styles.Err
+			if d.delta() < 0 {
+				sign = "-"
+				style = styles.Success
+			}
+			parts = append(parts, d.key.String()+" "+style.Render(sign+d.delta().Abs().Round(time.Millisecond).String()))
+		}
+		lines = append(lines, styles.Dimmed.Render("duration: ")+strings.Join(parts, styles.Dimmed.Render(", ")))
+	}
+	return lines
+}
+
+// joinKeys lists up to n keys, summarising the rest.
+func joinKeys(keys []outcomeKey, n int) string {
+	names := make([]string, 0, min(len(keys), n)+1)
+	for i, k := range keys {
+		if i == n {
+			names = append(names, fmt.Sprintf("+%d more", len(keys)-n))
+			break
+		}
+		names = append(names, k.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,100 @@
+package testchanged
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectOutcomes(t *testing.T) {
+	got := collectOutcomes(goTestOutput, analyzeOutput(goTestOutput))
+
+	want := map[outcomeKey]outcome{
+		{pkg: "example.com/calc", name: "TestAdd"}:     {},
+		{pkg: "example.com/calc", name: "TestSub"}:     {failed: true, duration: 10 * time.Millisecond},
+		{pkg: "example.com/calc", name: "TestSub/neg"}: {failed: true},
+		{pkg: "example.com/calc", name: "TestSub/pos"}: {},
+		{pkg: "example.com/boom"}:                      {failed: true, duration: 4 * time.Millisecond},
+		{pkg: "example.com/fine"}:                      {duration: 100 * time.Millisecond},
+		{pkg: "example.com/none"}:                      {skipped: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectOutcomes =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	a := outcomeKey{pkg: "example.com/m", name: "TestA"}
+	b := outcomeKey{pkg: "example.com/m", name: "TestB"}
+	c := outcomeKey{pkg: "example.com/m", name: "TestC"}
+	d := outcomeKey{pkg: "example.com/m", name: "TestD"}
+	e := outcomeKey{pkg: "example.com/m", name: "TestE"}
+
+	prev := map[outcomeKey]outcome{
+		a: {duration: 100 * time.Millisecond},
+		b: {failed: true, duration: 50 * time.Millisecond},
+		c: {failed: true},
+		d: {duration: 2 * time.Second},
+	}
+	cur := map[outcomeKey]outcome{
+		a: {failed: true, duration: 105 * time.Millisecond},
+		b: {duration: 20 * time.Millisecond},
+		c: {failed: true},
+		d: {duration: 3 * time.Second},
+		e: {failed: true},
+	}
+
+	got := compareRuns(prev, cur)
+	if want := []outcomeKey{a, e}; !reflect.DeepEqual(got.newlyFailing, want) {
+		t.Errorf("newlyFailing = %v, want %v", got.newlyFailing, want)
+	}
+	if want := []outcomeKey{b}; !reflect.DeepEqual(got.fixed, want) {
+		t.Errorf("fixed = %v, want %v", got.fixed, want)
+	}
+	if want := []outcomeKey{c}; !reflect.DeepEqual(got.stillFailing, want) {
+		t.Errorf("stillFailing = %v, want %v", got.stillFailing, want)
+	}
+	// TestA's 5ms change is below the noise threshold.
+	want := []durationChange{
+		{key: d, old: 2 * time.Second, new: 3 * time.Second},
+		{key: b, old: 50 * time.Millisecond, new: 20 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got.changes, want) {
+		t.Errorf("changes = %v, want %v", got.changes, want)
+	}
+}
+
+func TestResults_ComparesWithPreviousRunOfSameTargets(t *testing.T) {
+	m := New()
+	m.runKey = "./calc"
+
+	r, _ := m.Update(testBatchMsg{lines: goTestOutput})
+	m = r.(Model)
+	if m.comparison != nil {
+		t.Fatal("expected no comparison for the first run")
+	}
+
+	fixed := []string{
+		"=== RUN   TestAdd",
+		"--- PASS: TestAdd (0.00s)",
+		"=== RUN   TestSub",
+		"--- PASS: TestSub (0.01s)",
+		"PASS",
+		"ok      example.com/calc        0.012s",
+	}
+	r, _ = m.Update(testBatchMsg{lines: fixed})
+	m = r.(Model)
+	if m.comparison == nil {
+		t.Fatal("expected a comparison for the second run")
+	}
+	if got := len(m.comparison.fixed); got != 1 {
+		t.Errorf("fixed = %v, want TestSub only", m.comparison.fixed)
+	}
+
+	m.runKey = "./other"
+	r, _ = m.Update(testBatchMsg{lines: fixed})
+	m = r.(Model)
+	if m.comparison != nil {
+		t.Error("expected no comparison for different targets")
+	}
+}
@@ -0,0 +1,234 @@
+package testchanged
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// failureNoise matches the parts of test output that differ between runs
+// of the same failure: durations, addresses and goroutine IDs.
+var failureNoise = regexp.MustCompile(`\(\d+(\.\d+)?s\)|0x[0-9a-f]+|goroutine \d+`)
+
+// flakeFailure is one distinct failure seen while hunting.
+type flakeFailure struct {
+	output []string
+	count  int
+	first  int // 1-based run that first produced it
+}
+
+// flakeTally is the progress of a flake hunt.
+type flakeTally struct {
+	test     outcomeKey
+	runs     int // planned runs; 0 runs until the first failure
+	passed   int
+	failed   int
+	failures []flakeFailure
+	stopped  bool // cancelled before finishing
+}
+
+func (t flakeTally) done() int { return t.passed + t.failed }
+
+// failureRate is the fraction of finished runs that failed.
+func (t flakeTally) failureRate() float64 {
+	if t.done() == 0 {
+		return 0
+	}
+	return float64(t.failed) / float64(t.done())
+}
+
+// summary is a one-line description of the tally.
+func (t flakeTally) summary() string {
+	runs := fmt.Sprintf("%d/%d runs", t.done(), t.runs)
+	if t.runs == 0 {
+		runs = fmt.Sprintf("%d runs", t.done())
+	}
+	s := fmt.Sprintf("%s  %d passed  %d failed  %.1f%% failure rate", runs, t.passed, t.failed, t.failureRate()*100)
+	if t.stopped {
+		s += "  (stopped)"
+	}
+	return s
+}
+
+// lines renders the distinct failures for the results view.
+func (t flakeTally) lines() []string {
+	lines := []string{t.test.String() + ": " + t.summary()}
+	for i, f := range t.failures {
+		lines = append(lines, "", fmt.Sprintf("=== FAILURE %d of %d (%d run(s), first on run %d)",
+			i+1, len(t.failures), f.count, f.first))
+		lines = append(lines, f.output...)
+		// The test binary doesn't print go test's package summary; add it so
+		// the output is attributed to the package.
+		lines = append(lines, "FAIL\t"+t.test.pkg)
+	}
+	return lines
+}
+
+type flakeTallyMsg struct {
+	hunt  *flakeHunt
+	tally flakeTally
+}
+
+type flakeDoneMsg struct {
+	tally flakeTally
+	err   error
+}
+
+type testsListedMsg struct {
+	tests []outcomeKey
+	err   error
+}
+
+// flakeHunt runs one test repeatedly, publishing coalesced tallies like
+// testRun does for output.
+type flakeHunt struct {
+	updates chan flakeTally
+	done    chan flakeDoneMsg
+}
+
+// wait returns a command that delivers the next tally, or the final
+// flakeDoneMsg once every run has finished.
+func (h *flakeHunt) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case t := <-h.updates:
+			return flakeTallyMsg{hunt: h, tally: t}
+		case msg := <-h.done:
+			return msg
+		}
+	}
+}
+
+func (h *flakeHunt) publish(t flakeTally) {
+	select {
+	case <-h.updates:
+	default:
+	}
+	h.updates <- t
+}
+
+// runPattern builds a -run pattern matching exactly the test or subtest.
+func runPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// listTests lists the top-level tests in targets without running them.
+func listTests(targets []string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := exec.Command("go", append([]string{"test", "-list", "^Test"}, targets...)...).CombinedOutput()
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		if err != nil {
+			return testsListedMsg{err: fmt.Errorf("list tests: %s", tail(lines, 10))}
+		}
+		info := analyzeOutput(lines)
+		var tests []outcomeKey
+		for i, line := range lines {
+			if strings.HasPrefix(line, "Test") && info[i].pkg != "" {
+				tests = append(tests, outcomeKey{pkg: info[i].pkg, name: strings.TrimSpace(line)})
+			}
+		}
+		if len(tests) == 0 {
+			return testsListedMsg{err: fmt.Errorf("no tests found in selected targets")}
+		}
+		return testsListedMsg{tests: tests}
+	}
+}
+
+// huntFlakes compiles the test's package once and runs the binary runs
+// times in parallel, or until the first failure when runs is 0. Cancelling
+// ctx stops the hunt, keeping the tally so far.
+func huntFlakes(ctx context.Context, test outcomeKey, runs int) tea.Cmd {
+	return func() tea.Msg {
+		out, err := exec.Command("go", "list", "-f", "{{.Dir}}", test.pkg).Output()
+		if err != nil {
+			return flakeDoneMsg{err: fmt.Errorf("locate %s: %w", test.pkg, err)}
+		}
+		dir := strings.TrimSpace(string(out))
+
+		tmp, err := os.MkdirTemp("", "rig-flake-*")
+		if err != nil {
+			return flakeDoneMsg{err: err}
+		}
+		bin := filepath.Join(tmp, "test.bin")
+		if out, err := exec.CommandContext(ctx, "go", "test", "-c", "-o", bin, test.pkg).CombinedOutput(); err != nil {
+			_ = os.RemoveAll(tmp)
+			if ctx.Err() != nil {
+				return flakeDoneMsg{tally: flakeTally{test: test, runs: runs, stopped: true}}
+			}
+			return flakeDoneMsg{err: fmt.Errorf("build test binary: %s", tail(strings.Split(string(out), "\n"), 10))}
+		}
+
+		h := &flakeHunt{updates: make(chan flakeTally, 1), done: make(chan flakeDoneMsg, 1)}
+		go func() {
+			defer func() { _ = os.RemoveAll(tmp) }()
+
+			var (
+				mu     sync.Mutex
+				tally  = flakeTally{test: test, runs: runs}
+				seen   = make(map[string]int) // normalised output -> failure index
+				next   atomic.Int64
+				failed atomic.Bool
+				wg     sync.WaitGroup
+			)
+			workers := runtime.NumCPU()
+			if runs > 0 {
+				workers = min(workers, runs)
+			}
+			for range workers {
+				wg.Go(func() {
+					for {
+						run := int(next.Add(1))
+						if (runs > 0 && run > runs) || (runs == 0 && failed.Load()) || ctx.Err() != nil {
+							return
+						}
+						cmd := exec.CommandContext(ctx, bin, "-test.run", runPattern(test.name), "-test.count", "1", "-test.v")
+						cmd.Dir = dir
+						out, err := cmd.CombinedOutput()
+						if ctx.Err() != nil {
+							return // killed, so neither a pass nor a failure
+						}
+
+						mu.Lock()
+						if err == nil {
+							tally.passed++
+						} else {
+							failed.Store(true)
+							tally.failed++
+							lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+							key := failureNoise.ReplaceAllString(strings.Join(lines, "\n"), "")
+							if i, ok := seen[key]; ok {
+								tally.failures[i].count++
+								tally.failures[i].first = min(tally.failures[i].first, run)
+							} else {
+								seen[key] = len(tally.failures)
+								tally.failures = append(tally.failures, flakeFailure{output: lines, count: 1, first: run})
+							}
+						}
+						// Publish under the lock so tallies arrive in order.
+						snapshot := tally
+						snapshot.failures = append([]flakeFailure(nil), tally.failures...)
+						h.publish(snapshot)
+						mu.Unlock()
+					}
+				})
+			}
+			wg.Wait()
+			tally.stopped = ctx.Err() != nil
+			h.done <- flakeDoneMsg{tally: tally}
+		}()
+		return h.wait()()
+	}
+}
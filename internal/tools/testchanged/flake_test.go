@@ -0,0 +1,165 @@
+package testchanged
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestRunPattern(t *testing.T) {
+	tests := map[string]string{
+		"TestSub":           "^TestSub$",
+		"TestSub/neg":       "^TestSub$/^neg$",
+		"TestSub/a+b_(x)/y": `^TestSub$/^a\+b_\(x\)$/^y$`,
+	}
+	for name, want := range tests {
+		if got := runPattern(name); got != want {
+			t.Errorf("runPattern(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFlakeTallyLines(t *testing.T) {
+	tally := flakeTally{
+		test:   outcomeKey{pkg: "example.com/calc", name: "TestSub"},
+		runs:   10,
+		passed: 7,
+		failed: 1,
+		failures: []flakeFailure{{
+			output: []string{"=== RUN   TestSub", "    sub_test.go:12: got 1, want -1", "--- FAIL: TestSub (0.00s)", "FAIL"},
+			count:  1,
+			first:  4,
+		}},
+	}
+	if got, want := tally.summary(), "8/10 runs  7 passed  1 failed  12.5% failure rate"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	uncapped := flakeTally{passed: 5, stopped: true}
+	if got, want := uncapped.summary(), "5 runs  5 passed  0 failed  0.0% failure rate  (stopped)"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+
+	lines := tally.lines()
+	info := analyzeOutput(lines)
+	for i := 2; i < len(lines); i++ {
+		if info[i].pkg != "example.com/calc" {
+			t.Errorf("line %d %q not attributed to the package", i, lines[i])
+		}
+	}
+	if locs := parseLocations(lines, info, "/repo", ""); len(locs) != 1 || locs[0].line != 12 {
+		t.Errorf("parseLocations = %+v, want sub_test.go:12", locs)
+	}
+}
+
+func TestFlakeHunt_PickFromResults(t *testing.T) {
+	m := modelWithResults()
+	m.runnerName = "go"
+
+	m = press(m, keyRune('t'))
+	if m.state != stateFlakePick {
+		t.Fatalf("expected flake picker, got state %d", m.state)
+	}
+	want := []outcomeKey{
+		{pkg: "example.com/calc", name: "TestSub"},
+		{pkg: "example.com/calc", name: "TestSub/neg"},
+		{pkg: "example.com/calc", name: "TestAdd"},
+		{pkg: "example.com/calc", name: "TestSub/pos"},
+	}
+	if !reflect.DeepEqual(m.flakeTests, want) {
+		t.Errorf("flakeTests = %v, want failing tests first: %v", m.flakeTests, want)
+	}
+
+	m = press(m, keyRune('l'), keyRune('u'))
+	if m.flakeRuns != 200 || !m.flakeUntilFail {
+		t.Errorf("runs = %d, untilFail = %v; want 200, true", m.flakeRuns, m.flakeUntilFail)
+	}
+
+	m = press(m, keyCode(tea.KeyEscape))
+	if m.state != stateResults {
+		t.Errorf("esc should return to results, got state %d", m.state)
+	}
+}
+
+func TestFlakeHunt_Results(t *testing.T) {
+	m := New()
+	tally := flakeTally{test: outcomeKey{pkg: "example.com/calc", name: "TestSub"}, runs: 4, passed: 3, failed: 1,
+		failures: []flakeFailure{{output: []string{"--- FAIL: TestSub (0.00s)", "FAIL"}, count: 1, first: 2}}}
+
+	r, _ := m.Update(flakeDoneMsg{tally: tally})
+	m = r.(Model)
+	if m.state != stateResults || m.exitCode != 1 || m.flake == nil {
+		t.Fatalf("expected failed flake results, got state %d exit %d", m.state, m.exitCode)
+	}
+	if len(m.failureStarts) != 1 {
+		t.Errorf("failureStarts = %v, want one failure block", m.failureStarts)
+	}
+}
+
+// flakeModule creates a module with a passing and a failing test and makes
+// it the working directory.
+func flakeModule(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gittest.WriteFile(t, "go.mod", "module example.com/f\n\ngo 1.24\n")
+	gittest.WriteFile(t, "f_test.go", "package f\n\nimport \"testing\"\n\n"+
+		"func TestPass(t *testing.T) {}\n\nfunc TestFail(t *testing.T) { t.Fatal(\"boom\") }\n")
+}
+
+// finishHunt waits for the flake hunt started by cmd, calling onTally with
+// each tally, and returns the final message.
+func finishHunt(t *testing.T, cmd tea.Cmd, onTally func(flakeTally)) flakeDoneMsg {
+	t.Helper()
+	for {
+		switch msg := cmd().(type) {
+		case flakeTallyMsg:
+			onTally(msg.tally)
+			cmd = msg.hunt.wait()
+		case flakeDoneMsg:
+			return msg
+		default:
+			t.Fatalf("unexpected message %T", msg)
+		}
+	}
+}
+
+func TestHuntFlakes_UntilFailure(t *testing.T) {
+	flakeModule(t)
+
+	msg := finishHunt(t, huntFlakes(context.Background(), outcomeKey{pkg: "example.com/f", name: "TestFail"}, 0), func(flakeTally) {})
+	if msg.err != nil || msg.tally.failed == 0 || msg.tally.stopped {
+		t.Errorf("hunt = %+v, want it to stop at the failure", msg)
+	}
+}
+
+func TestHuntFlakes_Stop(t *testing.T) {
+	flakeModule(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A passing test with no run limit only ends when stopped.
+	msg := finishHunt(t, huntFlakes(ctx, outcomeKey{pkg: "example.com/f", name: "TestPass"}, 0), func(flakeTally) { cancel() })
+	if msg.err != nil || !msg.tally.stopped || msg.tally.passed == 0 || msg.tally.failed != 0 {
+		t.Errorf("hunt = %+v, want a stopped hunt with passes only", msg)
+	}
+}
+
+func TestFlakeHunt_EscStops(t *testing.T) {
+	m := New()
+	m.state = stateRunning
+	m.flake = &flakeTally{}
+	stopped := false
+	m.stopHunt = func() { stopped = true }
+
+	m = press(m, keyCode(tea.KeyEscape))
+	if !stopped || m.state != stateRunning || !strings.Contains(m.loadingMsg, "Stopping") {
+		t.Errorf("esc should stop the hunt and wait for it, state %d %q", m.state, m.loadingMsg)
+	}
+	r, _ := m.Update(flakeDoneMsg{tally: flakeTally{passed: 3, stopped: true}})
+	if m = r.(Model); m.stopHunt != nil || m.state != stateResults {
+		t.Errorf("a finished hunt should clear stopHunt, state %d", m.state)
+	}
+}
@@ -0,0 +1,163 @@
+package testchanged
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// fuzzFuncRe matches fuzz target declarations in test files.
+var fuzzFuncRe = regexp.MustCompile(`^func (Fuzz\w*)\(\w+ \*testing\.F\)`)
+
+// fuzzProgressRe matches the coordinator's periodic status line, e.g.
+// "fuzz: elapsed: 3s, execs: 1024 (341/sec), new interesting: 2 (total: 9)".
+var fuzzProgressRe = regexp.MustCompile(`fuzz: elapsed: (\S+), execs: (\d+) \((\d+)/sec\), new interesting: (\d+) \(total: (\d+)\)`)
+
+// fuzzTarget is one fuzz function in a package.
+type fuzzTarget struct {
+	pkg  string // import path
+	dir  string // package directory
+	name string
+}
+
+func (t fuzzTarget) String() string { return t.pkg + " " + t.name }
+
+// corpusDir is where go test stores generated inputs for the target.
+func (t fuzzTarget) corpusDir() string {
+	return filepath.Join(t.dir, "testdata", "fuzz", t.name)
+}
+
+type fuzzTargetsMsg struct {
+	targets []fuzzTarget
+	err     error
+}
+
+// fuzzDoneMsg reports a finished fuzz run and the corpus entries it added.
+type fuzzDoneMsg struct {
+	target fuzzTarget
+	lines  []string
+	corpus []string
+	err    error
+}
+
+// RunFuzz fuzzes a single target for budget. Only one package and one fuzz
+// function may be fuzzed per go test invocation.
+func (GoRunner) RunFuzz(t fuzzTarget, budget time.Duration) *exec.Cmd {
+	return exec.Command("go", "test", "-run", "^$", "-fuzz", "^"+t.name+"$",
+		"-fuzztime", budget.String(), t.pkg)
+}
+
+// findFuzzTargets lists the fuzz functions declared in the test files of
+// the packages matched by targets.
+func findFuzzTargets(targets []string) tea.Cmd {
+	return func() tea.Msg {
+		args := append([]string{"list", "-f", "{{.ImportPath}}\t{{.Dir}}\t{{join .TestGoFiles \" \"}} {{join .XTestGoFiles \" \"}}"}, targets...)
+		out, err := exec.Command("go", args...).Output()
+		if err != nil {
+			return fuzzTargetsMsg{err: fmt.Errorf("go list: %w", err)}
+		}
+
+		var found []fuzzTarget
+		for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+			pkg, rest, ok := strings.Cut(line, "\t")
+			if !ok {
+				continue
+			}
+			dir, files, _ := strings.Cut(rest, "\t")
+			for _, f := range strings.Fields(files) {
+				for _, name := range fuzzFuncs(filepath.Join(dir, f)) {
+					found = append(found, fuzzTarget{pkg: pkg, dir: dir, name: name})
+				}
+			}
+		}
+		if len(found) == 0 {
+			return fuzzTargetsMsg{err: fmt.Errorf("no fuzz targets found in selected packages")}
+		}
+		sort.SliceStable(found, func(i, j int) bool { return found[i].pkg < found[j].pkg })
+		return fuzzTargetsMsg{targets: found}
+	}
+}
+
+// fuzzFuncs returns the names of the fuzz functions declared in file.
+func fuzzFuncs(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if m := fuzzFuncRe.FindStringSubmatch(scanner.Text()); m != nil {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// corpusEntries returns the files currently in the target's corpus.
+func corpusEntries(t fuzzTarget) map[string]bool {
+	entries := make(map[string]bool)
+	des, err := os.ReadDir(t.corpusDir())
+	if err != nil {
+		return entries
+	}
+	for _, de := range des {
+		if !de.IsDir() {
+			entries[de.Name()] = true
+		}
+	}
+	return entries
+}
+
+// streamFuzz fuzzes t, streaming its output, and reports the corpus entries
+// that appeared during the run. Fuzzing always runs in the working tree so
+// that new corpus entries land where they can be committed.
+func streamFuzz(t fuzzTarget, budget time.Duration, cols, rows, maxLines int) tea.Cmd {
+	return func() tea.Msg {
+		before := corpusEntries(t)
+		run, err := startRun(GoRunner{}.RunFuzz(t, budget), cols, rows, maxLines, nil, nil)
+		if err != nil {
+			return testDoneMsg{err: err}
+		}
+		run.finish = func(msg testBatchMsg) tea.Msg {
+			var added []string
+			for name := range corpusEntries(t) {
+				if !before[name] {
+					added = append(added, filepath.Join(t.corpusDir(), name))
+				}
+			}
+			sort.Strings(added)
+			return fuzzDoneMsg{target: t, lines: msg.lines, corpus: added, err: msg.err}
+		}
+		return run.wait()()
+	}
+}
+
+// fuzzProgress is the latest coordinator status of a fuzz run.
+type fuzzProgress struct {
+	elapsed     string
+	execs       string
+	perSec      string
+	interesting string // total interesting inputs, i.e. new coverage found
+}
+
+// parseFuzzProgress returns the most recent status line in lines.
+func parseFuzzProgress(lines []string) (fuzzProgress, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if m := fuzzProgressRe.FindStringSubmatch(ansi.Strip(lines[i])); m != nil {
+			return fuzzProgress{elapsed: m[1], execs: m[2], perSec: m[3], interesting: m[5]}, true
+		}
+	}
+	return fuzzProgress{}, false
+}
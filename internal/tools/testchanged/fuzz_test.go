@@ -0,0 +1,89 @@
+package testchanged
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestFuzzFuncs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "x_test.go")
+	src := `package x
+
+func FuzzParse(f *testing.F) {}
+func FuzzHelper(n int) {}
+func TestParse(t *testing.T) {}
+func Fuzz(ff *testing.F) {}
+`
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fuzzFuncs(file), []string{"FuzzParse", "Fuzz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fuzzFuncs = %v, want %v", got, want)
+	}
+}
+
+func TestParseFuzzProgress(t *testing.T) {
+	lines := []string{
+		"fuzz: elapsed: 0s, gathering baseline coverage: 0/3 completed",
+		"fuzz: elapsed: 0s, execs: 3 (120/sec), new interesting: 0 (total: 3)",
+		"fuzz: elapsed: 3s, execs: 91210 (30395/sec), new interesting: 2 (total: 5)",
+		"PASS",
+	}
+	got, ok := parseFuzzProgress(lines)
+	want := fuzzProgress{elapsed: "3s", execs: "91210", perSec: "30395", interesting: "5"}
+	if !ok || got != want {
+		t.Errorf("parseFuzzProgress = %+v, %v; want %+v", got, ok, want)
+	}
+	if _, ok := parseFuzzProgress(lines[:1]); ok {
+		t.Error("expected no progress before fuzzing starts")
+	}
+}
+
+func TestFuzz_RunsQueueAndListsCorpus(t *testing.T) {
+	crash := fuzzTarget{pkg: "example.com/p", dir: "/src/p", name: "FuzzCrash"}
+	fine := fuzzTarget{pkg: "example.com/p", dir: "/src/p", name: "FuzzFine"}
+
+	m := New()
+	r, _ := m.Update(fuzzTargetsMsg{targets: []fuzzTarget{crash, fine}})
+	m = r.(Model)
+	if m.state != stateFuzzPick {
+		t.Fatalf("expected fuzz picker, got state %d", m.state)
+	}
+
+	// Choose both targets; the first starts and the second is queued.
+	m = press(m, keyCode(tea.KeySpace), keyRune('j'), keyCode(tea.KeySpace))
+	r, _ = m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateRunning || len(m.fuzzQueue) != 1 {
+		t.Fatalf("expected running with one queued target, got state %d queue %v", m.state, m.fuzzQueue)
+	}
+
+	r, _ = m.Update(fuzzDoneMsg{
+		target: crash,
+		lines:  []string{"--- FAIL: FuzzCrash (0.03s)", "FAIL    example.com/p        0.036s"},
+		corpus: []string{"/src/p/testdata/fuzz/FuzzCrash/1de061fa"},
+		err:    errors.New("exit status 1"),
+	})
+	m = r.(Model)
+	if m.state != stateRunning || len(m.fuzzQueue) != 0 {
+		t.Fatalf("expected the queued target to start, got state %d queue %v", m.state, m.fuzzQueue)
+	}
+
+	r, _ = m.Update(fuzzDoneMsg{target: fine, lines: []string{"PASS", "ok      example.com/p        3.029s"}})
+	m = r.(Model)
+	if m.state != stateResults || m.exitCode != 1 {
+		t.Fatalf("expected failed results, got state %d exit %d", m.state, m.exitCode)
+	}
+	out := strings.Join(m.output, "\n")
+	for _, want := range []string{"=== FUZZ example.com/p FuzzCrash", "=== FUZZ example.com/p FuzzFine", "/src/p/testdata/fuzz/FuzzCrash/1de061fa"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package testchanged
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -68,3 +70,26 @@ func changedFiles(base string) ([]string, error) {
 
 	return result, nil
 }
+
+// addWorktree checks out rev into a new detached worktree under the system
+// temp directory and returns its path. Callers must removeWorktree it.
+func addWorktree(rev string) (string, error) {
+	dir, err := os.MkdirTemp("", "rig-worktree-*")
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "worktree", "add", "--detach", dir, rev)
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("worktree add: %s", strings.TrimSpace(buf.String()))
+	}
+	return dir, nil
+}
+
+// removeWorktree deletes a worktree created by addWorktree.
+func removeWorktree(dir string) {
+	_ = exec.Command("git", "worktree", "remove", "--force", dir).Run()
+	_ = os.RemoveAll(dir)
+}
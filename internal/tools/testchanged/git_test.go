@@ -0,0 +1,25 @@
+package testchanged
+
+import (
+	"testing"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+// gitRepo creates a repository with two commits in a temp dir and makes it
+// the working directory: a.go is modified, b.go committed and c.go staged.
+func gitRepo(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	git := gittest.Git(t)
+	git("init", "-q")
+	gittest.WriteFile(t, "a.go", "package a\n")
+	git("add", ".")
+	git("commit", "-qm", "a")
+	gittest.WriteFile(t, "b.go", "package a\n")
+	git("add", ".")
+	git("commit", "-qm", "b")
+	gittest.WriteFile(t, "c.go", "package a\n")
+	git("add", "c.go")
+	gittest.WriteFile(t, "a.go", "package a // edited\n")
+}
@@ -0,0 +1,156 @@
+package testchanged
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// locationRe matches "path/file.go:12:" and "path/file.go:12:3:" as printed
+// by t.Error and the compiler, and "/abs/file.go:12 +0x1d" in panic traces.
+var locationRe = regexp.MustCompile(`(\S+\.go):(\d+)(?::(\d+))?(?::|\s+\+0x[0-9a-f]+)`)
+
+// location is a source position mentioned in failure output.
+type location struct {
+	file string // absolute path
+	line int
+	col  int
+	msg  string
+}
+
+// String formats the location as a quickfix entry.
+func (l location) String() string {
+	col := max(l.col, 1)
+	return fmt.Sprintf("%s:%d:%d: %s", l.file, l.line, col, l.msg)
+}
+
+// parseLocations extracts source locations from failing lines of output.
+// Bare file names (as printed by t.Error) are resolved against the package
+// directory under cwd; panic frames outside it (the runtime, the module
+// cache) are skipped. When the tests ran in a snapshot, snapshotDir is the
+// snapshot's equivalent of cwd and paths under it are mapped back to cwd.
+func parseLocations(lines []string, info []lineInfo, cwd, snapshotDir string) []location {
+	if cwd == "" {
+		return nil
+	}
+	module := modulePath()
+
+	seen := make(map[string]bool)
+	var locs []location
+	for i, raw := range lines {
+		if !info[i].failure {
+			continue
+		}
+		line := ansi.Strip(raw)
+		m := locationRe.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		file := line[m[2]:m[3]]
+		n, _ := strconv.Atoi(line[m[4]:m[5]])
+		col := 0
+		if m[6] >= 0 {
+			col, _ = strconv.Atoi(line[m[6]:m[7]])
+		}
+
+		if snapshotDir != "" && filepath.IsAbs(file) {
+			if rel, err := filepath.Rel(snapshotDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = filepath.Join(cwd, rel)
+			}
+		}
+		switch {
+		case filepath.IsAbs(file):
+			if rel, err := filepath.Rel(cwd, file); err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+		case !strings.Contains(file, "/"):
+			file = filepath.Join(cwd, packageDir(info[i].pkg, module), file)
+		default:
+			file = filepath.Join(cwd, file)
+		}
+
+		msg := strings.TrimSpace(line[m[1]:])
+		if strings.Contains(line[m[0]:m[1]], "+0x") || msg == "" {
+			// Panic frames carry the function name on the line before.
+			if i > 0 {
+				msg = strings.TrimSpace(ansi.Strip(lines[i-1]))
+			}
+		}
+
+		loc := location{file: file, line: n, col: col, msg: msg}
+		if key := loc.String(); !seen[key] {
+			seen[key] = true
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// modulePath reads the module path from go.mod in the working directory.
+func modulePath() string {
+	f, err := os.Open("go.mod")
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(mod), `"`)
+		}
+	}
+	return ""
+}
+
+// packageDir maps an import path inside module to its directory relative to
+// the module root.
+func packageDir(pkg, module string) string {
+	if module == "" || pkg == module {
+		return "."
+	}
+	if rel, ok := strings.CutPrefix(pkg, module+"/"); ok {
+		return rel
+	}
+	return "."
+}
+
+// editorCommand builds a command opening file at line in $VISUAL or $EDITOR,
+// using the line syntax the editor understands.
+func editorCommand(file string, line int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	pos := file + ":" + strconv.Itoa(line)
+	switch filepath.Base(args[0]) {
+	case "code", "code-insiders", "codium", "cursor":
+		args = append(args, "-g", pos)
+	case "subl", "zed", "hx", "helix":
+		args = append(args, pos)
+	default:
+		args = append(args, "+"+strconv.Itoa(line), file)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+// quickfix formats locs in file:line:col: message form, which both vim's
+// default errorformat and Emacs compilation mode understand.
+func quickfix(locs []location) string {
+	var b strings.Builder
+	for _, l := range locs {
+		b.WriteString(l.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package testchanged
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestParseLocations(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(cwd, "boom_test.go")
+
+	lines := []string{
+		"=== RUN   TestSub",
+		"    sub_test.go:12: got 1, want -1",
+		"--- FAIL: TestSub (0.00s)",
+		"panic: boom",
+		"example.com/calc.TestBoom(0xc000102000)",
+		"        " + abs + ":8 +0x25",
+		"runtime.goexit()",
+		"        /usr/local/go/src/runtime/asm_amd64.s:1700 +0x1",
+		"        /usr/local/go/src/testing/testing.go:1690 +0x10",
+		"FAIL    example.com/calc        0.004s",
+		"ok      example.com/fine        0.100s",
+	}
+	info := analyzeOutput(lines)
+
+	got := parseLocations(lines, info, cwd, "")
+	want := []location{
+		{file: filepath.Join(cwd, "sub_test.go"), line: 12, msg: "got 1, want -1"},
+		{file: abs, line: 8, msg: "example.com/calc.TestBoom(0xc000102000)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLocations =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseLocations_Snapshot(t *testing.T) {
+	cwd := filepath.FromSlash("/work/repo/pkg")
+	snap := filepath.FromSlash("/tmp/rig-worktree-1/pkg")
+	lines := []string{
+		"=== RUN   TestBoom",
+		"panic: boom",
+		"example.com/calc.TestBoom(0xc000102000)",
+		"        " + filepath.Join(snap, "boom_test.go") + ":8 +0x25",
+		"example.com/calc.helper()",
+		"        " + filepath.FromSlash("/tmp/rig-worktree-1/other/h.go") + ":3 +0x1",
+		"FAIL    example.com/calc        0.004s",
+	}
+	got := parseLocations(lines, analyzeOutput(lines), cwd, snap)
+	want := []location{
+		{file: filepath.Join(cwd, "boom_test.go"), line: 8, msg: "example.com/calc.TestBoom(0xc000102000)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLocations =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	tests := []struct{ pkg, module, want string }{
+		{"example.com/m", "example.com/m", "."},
+		{"example.com/m/internal/a", "example.com/m", "internal/a"},
+		{"other.com/x", "example.com/m", "."},
+		{"example.com/m/a", "", "."},
+	}
+	for _, tt := range tests {
+		if got := packageDir(tt.pkg, tt.module); got != tt.want {
+			t.Errorf("packageDir(%q, %q) = %q, want %q", tt.pkg, tt.module, got, tt.want)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor string
+		want   []string
+	}{
+		{"vim", []string{"vim", "+12", "/x/a.go"}},
+		{"emacs -nw", []string{"emacs", "-nw", "+12", "/x/a.go"}},
+		{"code --wait", []string{"code", "--wait", "-g", "/x/a.go:12"}},
+		{"hx", []string{"hx", "/x/a.go:12"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("VISUAL", "")
+		t.Setenv("EDITOR", tt.editor)
+		if got := editorCommand("/x/a.go", 12).Args; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EDITOR=%q: args = %q, want %q", tt.editor, got, tt.want)
+		}
+	}
+}
+
+// exportWith delivers the result of an export command to m.
+func exportWith(m tea.Model, cmd tea.Cmd) Model {
+	r, _ := m.Update(cmd())
+	return r.(Model)
+}
+
+func TestExportQuickfix(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	m := modelWithResults()
+	m.locations = []location{{file: "/repo/a_test.go", line: 3, msg: "boom"}}
+
+	m = exportWith(m.exportQuickfix())
+	if m.exportDir == "" || m.errSplash != "" {
+		t.Fatalf("export failed: %q", m.errSplash)
+	}
+	path := filepath.Join(m.exportDir, "quickfix")
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "/repo/a_test.go:3:1: boom\n" {
+		t.Errorf("quickfix = %q (%v)", data, err)
+	}
+	if !strings.Contains(m.notice, "vim -q "+path) {
+		t.Errorf("notice = %q, want the path", m.notice)
+	}
+	if _, cmd := m.exportQuickfix(); cmd().(exportDoneMsg).dir != m.exportDir {
+		t.Error("later exports should reuse the directory")
+	}
+}
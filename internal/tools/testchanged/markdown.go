@@ -0,0 +1,126 @@
+package testchanged
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxExcerptLines bounds the failure excerpt exported for each package.
+const maxExcerptLines = 40
+
+// runReport is everything the Markdown export needs to describe a run.
+type runReport struct {
+	runner   string
+	branch   string
+	base     string
+	scope    string
+	files    []string
+	targets  []string
+	snapshot string
+	passed   bool
+	elapsed  time.Duration
+	output   []string
+	info     []lineInfo
+}
+
+// markdown renders the report for pasting into a PR description. Failure
+// excerpts are collapsed so the summary stays short.
+func (r runReport) markdown() string {
+	var b strings.Builder
+	result := "✅ Tests passed"
+	if !r.passed {
+		result = "❌ Tests failed"
+	}
+	fmt.Fprintf(&b, "### %s\n\n", result)
+	fmt.Fprintf(&b, "- Runner: %s, %d target(s) in %.1fs\n", r.runner, len(r.targets), r.elapsed.Seconds())
+	fmt.Fprintf(&b, "- Scope: %s\n", r.scope)
+	if r.base != "" {
+		fmt.Fprintf(&b, "- Base: `origin/%s` (merge base `%s`)\n", r.branch, shortSHA(r.base))
+	}
+	if r.snapshot != "" {
+		fmt.Fprintf(&b, "- Snapshot: `%s`\n", r.snapshot)
+	}
+
+	writeList := func(summary string, items []string) {
+		fmt.Fprintf(&b, "\n<details>\n<summary>%s (%d)</summary>\n\n", summary, len(items))
+		for _, item := range items {
+			fmt.Fprintf(&b, "- `%s`\n", item)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	writeList("Changed files", r.files)
+	writeList("Targets", r.targets)
+
+	var pkgs []packageResult
+	for _, line := range r.output {
+		if p, ok := parsePackageResult(line); ok {
+			pkgs = append(pkgs, p)
+		}
+	}
+	if len(pkgs) > 0 {
+		b.WriteString("\n| Package | Result | Duration |\n| --- | --- | --- |\n")
+		for _, p := range pkgs {
+			status := "✅ pass"
+			switch {
+			case p.skipped:
+				status = "➖ no tests"
+			case !p.passed:
+				status = "❌ fail"
+			}
+			duration := ""
+			if p.duration > 0 {
+				duration = p.duration.String()
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", p.pkg, status, duration)
+		}
+	}
+
+	for _, ex := range failureExcerpts(r.output, r.info) {
+		fmt.Fprintf(&b, "\n<details>\n<summary>Failure output: %s</summary>\n\n```\n", ex.pkg)
+		for _, line := range ex.lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		b.WriteString("```\n\n</details>\n")
+	}
+	return b.String()
+}
+
+type excerpt struct {
+	pkg   string
+	lines []string
+}
+
+// failureExcerpts groups failure lines by package, in output order, keeping
+// at most maxExcerptLines per package.
+func failureExcerpts(lines []string, info []lineInfo) []excerpt {
+	var excerpts []excerpt
+	index := make(map[string]int)
+	for i, line := range lines {
+		if !info[i].failure {
+			continue
+		}
+		pkg := info[i].pkg
+		if pkg == "" {
+			// Trailing lines such as the final "FAIL" belong to no package.
+			continue
+		}
+		j, ok := index[pkg]
+		if !ok {
+			j = len(excerpts)
+			index[pkg] = j
+			excerpts = append(excerpts, excerpt{pkg: pkg})
+		}
+		ex := &excerpts[j]
+		switch {
+		case len(ex.lines) < maxExcerptLines:
+			ex.lines = append(ex.lines, ansi.Strip(line))
+		case len(ex.lines) == maxExcerptLines:
+			ex.lines = append(ex.lines, "...")
+		}
+	}
+	return excerpts
+}
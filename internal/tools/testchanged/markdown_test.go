@@ -0,0 +1,73 @@
+package testchanged
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunReportMarkdown(t *testing.T) {
+	r := runReport{
+		runner:  "go",
+		branch:  "main",
+		base:    "0123456789abcdef",
+		files:   []string{"calc/sub.go"},
+		targets: []string{"./calc", "./boom"},
+		elapsed: 1500 * time.Millisecond,
+		output:  goTestOutput,
+		info:    analyzeOutput(goTestOutput),
+	}
+	md := r.markdown()
+
+	for _, want := range []string{
+		"### ❌ Tests failed",
+		"- Runner: go, 2 target(s) in 1.5s",
+		"- Base: `origin/main` (merge base `0123456`)",
+		"<summary>Changed files (1)</summary>",
+		"- `calc/sub.go`",
+		"<summary>Targets (2)</summary>",
+		"| `example.com/calc` | ❌ fail | 12ms |",
+		"| `example.com/fine` | ✅ pass | 100ms |",
+		"| `example.com/none` | ➖ no tests |  |",
+		"<summary>Failure output: example.com/calc</summary>",
+		"    sub_test.go:12: got 1, want -1",
+		"<summary>Failure output: example.com/boom</summary>",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "=== RUN   TestAdd") {
+		t.Errorf("markdown excerpt includes passing test output:\n%s", md)
+	}
+}
+
+func TestFailureExcerptsTruncate(t *testing.T) {
+	lines := make([]string, 0, maxExcerptLines+11)
+	for range maxExcerptLines + 10 {
+		lines = append(lines, "# example.com/big")
+	}
+	lines = append(lines, "FAIL    example.com/big [build failed]")
+
+	got := failureExcerpts(lines, analyzeOutput(lines))
+	if len(got) != 1 || len(got[0].lines) != maxExcerptLines+1 || got[0].lines[maxExcerptLines] != "..." {
+		t.Errorf("failureExcerpts = %+v, want %d lines then ...", got, maxExcerptLines)
+	}
+}
+
+func TestResults_MarkdownExportUsesTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	m := exportWith(modelWithResults().Update(keyRune('m')))
+	if m.exportDir == "" || m.errSplash != "" {
+		t.Fatalf("export failed: %q", m.errSplash)
+	}
+	data, err := os.ReadFile(filepath.Join(m.exportDir, "summary.md"))
+	if err != nil || !strings.Contains(string(data), "Tests failed") {
+		t.Errorf("summary = %q (%v)", data, err)
+	}
+	if !strings.Contains(m.notice, m.exportDir) {
+		t.Errorf("notice = %q, want the path", m.notice)
+	}
+}
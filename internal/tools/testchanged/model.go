@@ -1,9 +1,12 @@
 package testchanged
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,9 +14,11 @@ import (
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/spinner"
 	"charm.land/bubbles/v2/stopwatch"
+	"charm.land/bubbles/v2/textinput"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 
 	"github.com/ryan-rushton/rig/internal/messages"
 	"github.com/ryan-rushton/rig/internal/registry"
@@ -36,6 +41,12 @@ const (
 	stateBrowse
 	stateRunning
 	stateResults
+	stateLocations
+	stateFuzzPick
+	stateScope
+	stateScopeRef
+	stateFlakePick
+	stateProfilePick
 )
 
 type keyMap struct {
@@ -46,21 +57,78 @@ func (k keyMap) ShortHelp() []key.Binding  { return k.bindings }
 func (k keyMap) FullHelp() [][]key.Binding { return nil }
 
 var browseEmptyKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "scope")),
 	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
 }}
 
 var browseKeys = keyMap{bindings: []key.Binding{
 	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run")),
+	key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "bench")),
+	key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "fuzz")),
+	key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "flake hunt")),
+	key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "profile")),
+	key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "snapshot")),
+	key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "order")),
+	key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "fail-fast")),
+	key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "scope")),
 	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
 }}
 
 var resultsKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+	key.NewBinding(key.WithKeys("n", "N"), key.WithHelp("n/N", "next/prev match")),
+	key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "filter")),
+	key.NewBinding(key.WithKeys("]", "["), key.WithHelp("]/[", "next/prev failure")),
+	key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open location")),
+	key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export quickfix")),
+	key.NewBinding(key.WithKeys("m", "y"), key.WithHelp("m/y", "markdown file/copy")),
+	key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "flake hunt")),
 	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rerun")),
 	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc/q", "back")),
 }}
 
+var locationKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open in $EDITOR")),
+	key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export quickfix")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back to results")),
+}}
+
+var fuzzPickKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("space"), key.WithHelp("space", "toggle")),
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "fuzz")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+}}
+
+var flakePickKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "hunt")),
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "runs")),
+	key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "until failure")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+}}
+
+var profilePickKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "profile")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+}}
+
+var scopeKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+	key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "commits")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+}}
+
+var refKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+}}
+
+var searchKeys = keyMap{bindings: []key.Binding{
+	key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+	key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}}
+
 var dismissKeys = keyMap{bindings: []key.Binding{
 	key.NewBinding(key.WithKeys("any"), key.WithHelp("any key", "dismiss")),
 }}
@@ -68,7 +136,11 @@ var dismissKeys = keyMap{bindings: []key.Binding{
 // Messages used by this tool.
 type targetsLoadedMsg struct {
 	runner  string
+	branch  string
+	base    string
+	files   []string
 	targets []string
+	stats   targetStats
 	err     error
 }
 
@@ -76,19 +148,55 @@ type testDoneMsg struct {
 	err error
 }
 
+type editorClosedMsg struct {
+	err error
+}
+
 // discoveredTarget groups a target with which runner found it.
 type discoveredTarget struct {
 	runner string
 	target string
 }
 
+// Options configures a test-changed session.
+type Options struct {
+	// BenchCount is the -count passed to go test when benchmarking.
+	BenchCount int
+	// Snapshot runs tests in a temporary copy of the working tree so that
+	// edits made during a run cannot affect it.
+	Snapshot bool
+	// FuzzTime is the -fuzztime budget for each fuzz target.
+	FuzzTime time.Duration
+	// FlakeRuns is how many times a flake hunt runs the chosen test.
+	FlakeRuns int
+	// Ordered runs targets one at a time, those that failed last time
+	// first and then slowest first.
+	Ordered bool
+	// FailFast stops running targets after the first failing one.
+	FailFast bool
+	// NotifyAfter is how long a run must take before its completion is
+	// announced with a terminal notification; zero disables it.
+	NotifyAfter time.Duration
+	// NotifySend also sends a desktop notification via notify-send.
+	NotifySend bool
+}
+
+// DefaultOptions returns the options used when launching from the home screen.
+func DefaultOptions() Options {
+	return Options{BenchCount: 6, FuzzTime: 30 * time.Second, FlakeRuns: 100, NotifyAfter: 30 * time.Second}
+}
+
 // Model is the test-changed TUI model.
+//
+// Update works on a copy of the Model, and earlier copies may still be in
+// use, so slices and maps are replaced rather than modified in place.
 type Model struct {
+	opts            Options
 	state           viewState
 	targets         []discoveredTarget
 	cursor          int
 	output          []string
-	maxOutput       int
+	maxOutput       int // lines of run output kept; older lines are dropped
 	browseViewport  viewport.Model
 	resultsViewport viewport.Model
 	errSplash       string
@@ -98,12 +206,79 @@ type Model struct {
 	loadingMsg      string
 	exitCode        int
 	runnerName      string
+	branch          string
+	base            string
+	changedFiles    []string
+	snapshotLabel   string // set when the last run used a snapshot
+	cwd             string // directory rig was started in; "" if unknown
+	exportDir       string // temp dir quickfix and Markdown exports are written to
+	benchRows       []benchRow
+	// results navigation: see search.go
+	lineInfo      []lineInfo
+	packages      []string
+	visible       []int // output indices shown in the results viewport
+	filter        int
+	search        textinput.Model
+	searching     bool
+	query         string
+	matches       []int // viewport rows containing a match
+	matchIdx      int
+	failureStarts []int // viewport rows where failure output begins
+	failureRow    int
+	locations     []location
+	locCursor     int
+	locViewport   viewport.Model
+	// fuzz mode: see fuzz.go
+	fuzzTargets  []fuzzTarget
+	fuzzChosen   []bool
+	fuzzCursor   int
+	fuzzViewport viewport.Model
+	fuzzQueue    []fuzzTarget
+	fuzzing      bool     // the current or last run was a fuzz run
+	fuzzOutput   []string // output of the fuzz targets finished so far
+	fuzzCorpus   []string
+	// change scope: see scope.go
+	scope        changeScope
+	scopeCursor  int
+	scopeCommits int
+	refs         []string
+	refCursor    int
+	refViewport  viewport.Model
+	// flake hunting: see flake.go
+	flakeTests     []outcomeKey
+	flakeCursor    int
+	flakeViewport  viewport.Model
+	flakeRuns      int
+	flakeUntilFail bool
+	stopHunt       context.CancelFunc // stops the running flake hunt
+	flakeReturn    viewState          // where esc in the picker goes back to
+	flake          *flakeTally        // set when the current or last run is a hunt
+	// profiling: see profile.go
+	profilePkgs     []string
+	profileCursor   int
+	profileViewport viewport.Model
+	profile         *profileDoneMsg // set when the last run was a profile
+	notice          string          // one-off status message, cleared by the next key
 	finishedIn      time.Duration
-	width           int
-	height          int
+	// runTargets are the targets of the current run, for reports.
+	runTargets []string
+	// runKey identifies the targets of the current run; history holds the
+	// outcomes of the last run of each set of targets for comparison.
+	runKey     string
+	stats      targetStats  // last outcome per target, persisted
+	seq        *runSequence // set while targets run one at a time
+	history    map[string]map[outcomeKey]outcome
+	comparison *runComparison
+	width      int
+	height     int
 }
 
 func New() Model {
+	return NewWithOptions(DefaultOptions())
+}
+
+// NewWithOptions returns a Model configured with opts.
+func NewWithOptions(opts Options) Model {
 	s := spinner.New()
 	s.Spinner = spinner.MiniDot
 	s.Style = styles.Selected
@@ -115,25 +290,58 @@ func New() Model {
 	bvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
 	bvp.KeyMap = viewport.KeyMap{}
 
+	lvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	lvp.KeyMap = viewport.KeyMap{}
+
+	fvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	fvp.KeyMap = viewport.KeyMap{}
+
+	refvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	refvp.KeyMap = viewport.KeyMap{}
+
+	flvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	flvp.KeyMap = viewport.KeyMap{}
+
+	pvp := viewport.New(viewport.WithWidth(80), viewport.WithHeight(20))
+	pvp.KeyMap = viewport.KeyMap{}
+
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.CharLimit = 200
+	ti.SetWidth(50)
+
 	h := help.New()
 	h.Styles.ShortKey = lipgloss.NewStyle().Foreground(styles.DimGray).Italic(true).Bold(true)
 	h.Styles.ShortDesc = styles.Help
 	h.Styles.ShortSeparator = styles.Help
 
+	cwd, _ := os.Getwd()
+
 	return Model{
+		opts:            opts,
+		cwd:             cwd,
 		state:           stateLoading,
-		maxOutput:       500,
+		maxOutput:       20000,
 		spinner:         s,
 		stopwatch:       sw,
 		browseViewport:  bvp,
 		resultsViewport: rvp,
+		locViewport:     lvp,
+		fuzzViewport:    fvp,
+		refViewport:     refvp,
+		flakeViewport:   flvp,
+		profileViewport: pvp,
+		flakeRuns:       opts.FlakeRuns,
+		scopeCommits:    1,
+		search:          ti,
 		help:            h,
+		history:         make(map[string]map[outcomeKey]outcome),
 		loadingMsg:      "Detecting default branch...",
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(loadTargets, m.spinner.Tick, m.stopwatch.Start())
+	return tea.Batch(loadTargets(m.scope), m.spinner.Tick, m.stopwatch.Start())
 }
 
 // startAsync transitions into a waiting state, resets the timer, and
@@ -150,22 +358,40 @@ func showError(m Model, err error) Model {
 	return m
 }
 
-func loadTargets() tea.Msg {
-	branch, err := detectDefaultBranch()
-	if err != nil {
-		return targetsLoadedMsg{err: fmt.Errorf("detect default branch: %w", err)}
+// loadTargets discovers the targets affected by the changes in scope. The
+// merge base is only required by the default scope; the others still record
+// it when available so benchmarks can use it.
+func loadTargets(scope changeScope) tea.Cmd {
+	return func() tea.Msg {
+		branch, base, err := defaultBase()
+		if err != nil && scope.kind == scopeMergeBase {
+			return targetsLoadedMsg{err: err}
+		}
+
+		files, err := scope.files(base)
+		if err != nil {
+			return targetsLoadedMsg{err: fmt.Errorf("changed files: %w", err)}
+		}
+		return findTargets(branch, base, files)
 	}
+}
 
-	base, err := mergeBase(branch)
+// defaultBase returns the default branch and its merge base with HEAD.
+func defaultBase() (branch, base string, err error) {
+	branch, err = detectDefaultBranch()
 	if err != nil {
-		return targetsLoadedMsg{err: fmt.Errorf("merge base: %w", err)}
+		return "", "", fmt.Errorf("detect default branch: %w", err)
 	}
-
-	files, err := changedFiles(base)
+	base, err = mergeBase(branch)
 	if err != nil {
-		return targetsLoadedMsg{err: fmt.Errorf("changed files: %w", err)}
+		return "", "", fmt.Errorf("merge base: %w", err)
 	}
+	return branch, base, nil
+}
 
+// findTargets asks the first runner that detects its build system and finds
+// targets for files.
+func findTargets(branch, base string, files []string) targetsLoadedMsg {
 	var targets []string
 	runnerName := ""
 	for _, r := range allRunners() {
@@ -179,55 +405,15 @@ func loadTargets() tea.Msg {
 		}
 	}
 
-	return targetsLoadedMsg{runner: runnerName, targets: targets}
+	return targetsLoadedMsg{runner: runnerName, branch: branch, base: base, files: files, targets: targets, stats: loadStats()}
 }
 
 type testBatchMsg struct {
-	lines []string
-	err   error
-}
-
-// streamLines returns a tea.Cmd that sends output lines one at a time,
-// allowing the TUI to render progressively.
-func streamLines(runner string, targets []string) tea.Cmd {
-	return func() tea.Msg {
-		var r TestRunner
-		for _, candidate := range allRunners() {
-			if candidate.Name() == runner {
-				r = candidate
-				break
-			}
-		}
-		if r == nil {
-			return testDoneMsg{err: fmt.Errorf("runner %q not found", runner)}
-		}
-
-		cmd := r.RunTests(targets)
-
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			return testDoneMsg{err: err}
-		}
-
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			return testDoneMsg{err: err}
-		}
-
-		if err := cmd.Start(); err != nil {
-			return testDoneMsg{err: err}
-		}
-
-		combined := io.MultiReader(stdout, stderr)
-		var lines []string
-		scanner := bufio.NewScanner(combined)
-		for scanner.Scan() {
-			lines = append(lines, scanner.Text())
-		}
-
-		exitErr := cmd.Wait()
-		return testBatchMsg{lines: lines, err: exitErr}
-	}
+	lines       []string
+	snapshot    string // label of the snapshot the tests ran in, if any
+	snapshotDir string // the snapshot's equivalent of the working directory
+	elapsed     time.Duration
+	err         error
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -244,12 +430,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		hPad := 6 // border(2) + padding(4) horizontal
-		// Results viewport: header(2 lines) + border/padding(4) + help+scroll(3)
 		m.resultsViewport.SetWidth(msg.Width - hPad)
-		m.resultsViewport.SetHeight(msg.Height - 9)
+		m.resizeResults()
 		// Browse viewport: title+blank(2) + subtitle+blank(2) + border/padding(4) + help+blank(2)
 		m.browseViewport.SetWidth(msg.Width - hPad)
 		m.browseViewport.SetHeight(msg.Height - 10)
+		m.locViewport.SetWidth(msg.Width - hPad)
+		m.locViewport.SetHeight(msg.Height - 10)
+		m.fuzzViewport.SetWidth(msg.Width - hPad)
+		m.fuzzViewport.SetHeight(msg.Height - 10)
+		m.refViewport.SetWidth(msg.Width - hPad)
+		m.refViewport.SetHeight(msg.Height - 10)
+		m.flakeViewport.SetWidth(msg.Width - hPad)
+		m.flakeViewport.SetHeight(msg.Height - 10)
+		m.profileViewport.SetWidth(msg.Width - hPad)
+		m.profileViewport.SetHeight(msg.Height - 10)
 		return m, nil
 
 	case targetsLoadedMsg:
@@ -259,6 +454,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.state = stateBrowse
 		m.runnerName = msg.runner
+		m.branch = msg.branch
+		m.base = msg.base
+		m.changedFiles = msg.files
+		m.stats = msg.stats
 		m.targets = make([]discoveredTarget, 0, len(msg.targets)+1)
 		if len(msg.targets) > 0 {
 			m.targets = append(m.targets, discoveredTarget{runner: msg.runner, target: "All"})
@@ -268,17 +467,209 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case testDoneMsg:
+		// Only sent when a run could not be started.
+		cleanup := m.endSequence()
+		if msg.err != nil {
+			m = showError(m, msg.err)
+		}
+		return m, cleanup
+
+	case sequenceSnapshotMsg:
+		if msg.err != nil {
+			m.seq = nil
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		seq := *m.seq
+		seq.snap = &msg.snap
+		m.seq = &seq
+		m.loadingMsg = seq.label()
+		return m, m.runTests([]string{seq.current})
+
+	case outputMsg:
+		m.output = msg.apply(m.output)
+		return m, msg.run.wait()
+
 	case testBatchMsg:
-		m.output = append(m.output, msg.lines...)
+		if m.seq != nil {
+			return m.continueSequence(msg)
+		}
+		save := m.recordStats(runStats(m.runTargets, msg, time.Now()))
+		m = m.showTestResults(msg)
+		return m, tea.Batch(save, m.notifyDone())
+
+	case refsLoadedMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		if len(msg.refs) == 0 {
+			m = showError(m, fmt.Errorf("no branches or tags found"))
+			return m, nil
+		}
+		m.state = stateScopeRef
+		m.refs = msg.refs
+		m.refCursor = 0
+		m.refViewport.SetYOffset(0)
+		return m, nil
+
+	case packagesListedMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		if len(msg.pkgs) == 1 {
+			return m.startProfile(msg.pkgs[0])
+		}
+		m.state = stateProfilePick
+		m.profilePkgs = msg.pkgs
+		m.profileCursor = 0
+		m.profileViewport.SetYOffset(0)
+		return m, nil
+
+	case profileDoneMsg:
+		if msg.dir == "" && msg.err != nil {
+			// The profile directory could not be created, so nothing ran.
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.profile = &msg
+		m.exitCode = 0
+		if msg.err != nil {
+			m.exitCode = 1
+		}
+		m.benchRows = nil
+		m.snapshotLabel = ""
+		m.comparison = nil
 		m.state = stateResults
 		m.finishedIn = m.stopwatch.Elapsed()
+		m.setOutput(msg.lines)
+		m.locations = parseLocations(m.output, m.lineInfo, m.cwd, "")
+		m.locCursor = 0
+		m.resizeResults()
+		m.resultsViewport.GotoTop()
+		return m, m.notifyDone()
+
+	case testsListedMsg:
 		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m = m.pickFlakeTest(msg.tests, stateBrowse)
+		return m, nil
+
+	case flakeTallyMsg:
+		m.flake = &msg.tally
+		return m, msg.hunt.wait()
+
+	case flakeDoneMsg:
+		if m.stopHunt != nil {
+			m.stopHunt() // releases the context
+			m.stopHunt = nil
+		}
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.flake = &msg.tally
+		m.exitCode = 0
+		if msg.tally.failed > 0 {
 			m.exitCode = 1
-		} else {
-			m.exitCode = 0
 		}
-		m.resultsViewport.SetContent(colorizeOutput(m.output))
+		m.benchRows = nil
+		m.snapshotLabel = ""
+		m.comparison = nil
+		m.state = stateResults
+		m.finishedIn = m.stopwatch.Elapsed()
+		m.setOutput(msg.tally.lines())
+		m.locations = parseLocations(m.output, m.lineInfo, m.cwd, "")
+		m.locCursor = 0
+		m.resizeResults()
+		m.resultsViewport.GotoTop()
+		return m, m.notifyDone()
+
+	case fuzzTargetsMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.state = stateFuzzPick
+		m.fuzzTargets = msg.targets
+		m.fuzzChosen = make([]bool, len(msg.targets))
+		m.fuzzCursor = 0
+		return m, nil
+
+	case fuzzDoneMsg:
+		m.fuzzOutput = append(m.fuzzOutput, "=== FUZZ "+msg.target.String())
+		m.fuzzOutput = append(m.fuzzOutput, msg.lines...)
+		m.fuzzCorpus = append(m.fuzzCorpus, msg.corpus...)
+		if msg.err != nil {
+			m.exitCode = 1
+		}
+		if len(m.fuzzQueue) > 0 {
+			next := m.fuzzQueue[0]
+			m.fuzzQueue = m.fuzzQueue[1:]
+			m.loadingMsg = "Fuzzing " + next.String() + "..."
+			m.output = nil
+			return m, m.fuzz(next)
+		}
+		lines := m.fuzzOutput
+		if len(m.fuzzCorpus) > 0 {
+			lines = append(lines, "", "New corpus entries (commit them as regression inputs):")
+			for _, f := range m.fuzzCorpus {
+				if rel, err := filepath.Rel(m.cwd, f); err == nil {
+					f = rel
+				}
+				lines = append(lines, "  "+f)
+			}
+		}
+		m.benchRows = nil
+		m.snapshotLabel = ""
+		m.comparison = nil
+		m.state = stateResults
+		m.finishedIn = m.stopwatch.Elapsed()
+		m.setOutput(lines)
+		m.locations = parseLocations(m.output, m.lineInfo, m.cwd, "")
+		m.locCursor = 0
+		m.resizeResults()
 		m.resultsViewport.GotoBottom()
+		return m, m.notifyDone()
+
+	case benchDoneMsg:
+		if msg.err != nil {
+			m = showError(m, msg.err)
+			return m, nil
+		}
+		m.state = stateResults
+		m.finishedIn = m.stopwatch.Elapsed()
+		m.exitCode = 0
+		m.benchRows = msg.rows
+		m.setOutput(renderBenchTable(msg.rows, msg.notes))
+		m.locations = nil
+		m.comparison = nil
+		m.resizeResults()
+		m.resultsViewport.GotoTop()
+		return m, m.notifyDone()
+
+	case exportDoneMsg:
+		if msg.dir != "" {
+			m.exportDir = msg.dir
+		}
+		switch {
+		case msg.err != nil:
+			m.errSplash = fmt.Sprintf("export %s: %v", msg.kind, msg.err)
+		case msg.kind == "quickfix":
+			m.notice = fmt.Sprintf("wrote %d location(s) to %s (vim -q %s)", len(m.locations), msg.path, msg.path)
+		default:
+			m.notice = "wrote Markdown summary to " + msg.path
+		}
+		return m, nil
+
+	case editorClosedMsg:
+		if msg.err != nil {
+			m.errSplash = fmt.Sprintf("editor: %v", msg.err)
+		}
 		return m, nil
 
 	case tea.KeyPressMsg:
@@ -330,46 +721,667 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			}
 		case "enter":
 			if len(m.targets) > 0 {
-				var targets []string
-				if m.cursor == 0 {
-					// "All" selected — run all real targets.
-					for _, t := range m.targets[1:] {
-						targets = append(targets, t.target)
-					}
-				} else {
-					targets = []string{m.targets[m.cursor].target}
-				}
+				targets := m.selectedTargets()
 				m.output = nil
-				return startAsync(m, stateRunning, "Running tests...", streamLines(m.runnerName, targets))
+				m.clearRunMode()
+				m.runTargets = targets
+				m.runKey = strings.Join(targets, " ")
+				if (m.opts.Ordered || m.opts.FailFast) && len(targets) > 1 {
+					return m.startSequence(targets)
+				}
+				return startAsync(m, stateRunning, "Running tests...", m.runTests(targets))
 			}
+		case "b":
+			if len(m.targets) == 0 {
+				break
+			}
+			if m.runnerName != "go" {
+				m = showError(m, fmt.Errorf("benchmarks are only supported by the go runner"))
+				return m, nil
+			}
+			if m.base == "" {
+				m = showError(m, fmt.Errorf("benchmarks need a merge base with origin/main or origin/master"))
+				return m, nil
+			}
+			m.output = nil
+			m.clearRunMode()
+			return startAsync(m, stateRunning, "Running benchmarks at HEAD and merge base...",
+				runBenchmarks(m.selectedTargets(), m.base, m.opts.BenchCount))
+		case "z":
+			if len(m.targets) == 0 {
+				break
+			}
+			if m.runnerName != "go" {
+				m = showError(m, fmt.Errorf("fuzzing is only supported by the go runner"))
+				return m, nil
+			}
+			return startAsync(m, stateLoading, "Finding fuzz targets...", findFuzzTargets(m.selectedTargets()))
+		case "t":
+			if len(m.targets) == 0 {
+				break
+			}
+			if m.runnerName != "go" {
+				m = showError(m, fmt.Errorf("flake hunting is only supported by the go runner"))
+				return m, nil
+			}
+			return startAsync(m, stateLoading, "Listing tests...", listTests(m.selectedTargets()))
+		case "p":
+			if len(m.targets) == 0 {
+				break
+			}
+			if m.runnerName != "go" {
+				m = showError(m, fmt.Errorf("profiling is only supported by the go runner"))
+				return m, nil
+			}
+			return startAsync(m, stateLoading, "Listing packages...", listPackages(m.selectedTargets()))
+		case "s":
+			m.opts.Snapshot = !m.opts.Snapshot
+		case "o":
+			m.opts.Ordered = !m.opts.Ordered
+		case "f":
+			m.opts.FailFast = !m.opts.FailFast
+		case "c":
+			m.state = stateScope
+			m.scopeCursor = int(m.scope.kind)
 		case "r":
 			m.targets = nil
 			m.cursor = 0
-			return startAsync(m, stateLoading, "Detecting default branch...", loadTargets)
+			return startAsync(m, stateLoading, "Detecting default branch...", loadTargets(m.scope))
 		}
 
 	case stateRunning:
-		// No tool-level keys during test execution; ctrl+c handled at app level.
+		// Only flake hunts can be stopped; ctrl+c is handled at app level.
+		if msg.String() == "esc" && m.stopHunt != nil {
+			m.stopHunt()
+			m.loadingMsg = "Stopping flake hunt..."
+		}
 
 	case stateResults:
+		if m.searching {
+			return m.handleSearchKey(msg)
+		}
+		m.notice = ""
 		switch msg.String() {
-		case "q", "esc":
+		case "esc":
+			// Clear an active search or filter before leaving.
+			if m.query != "" || m.filter != filterAll {
+				m.query = ""
+				m.filter = filterAll
+				m.renderResults()
+				return m, nil
+			}
 			return m, func() tea.Msg { return messages.BackMsg{} }
+		case "q":
+			return m, func() tea.Msg { return messages.BackMsg{} }
+		case "/":
+			m.searching = true
+			m.search.SetValue(m.query)
+			m.search.CursorEnd()
+			return m, m.search.Focus()
+		case "n":
+			m.jumpMatch(1)
+		case "N":
+			m.jumpMatch(-1)
+		case "f":
+			m.filter = (m.filter + 1) % (filterFirstPkg + len(m.packages))
+			m.renderResults()
+			m.resultsViewport.GotoTop()
+		case "]":
+			m.jumpFailure(1)
+		case "[":
+			m.jumpFailure(-1)
+		case "t":
+			if m.runnerName != "go" {
+				m.notice = "flake hunting is only supported by the go runner"
+				break
+			}
+			tests := m.testsInOutput()
+			if len(tests) == 0 {
+				m.notice = "no test results in this output"
+				break
+			}
+			m = m.pickFlakeTest(tests, stateResults)
+		case "o":
+			if len(m.locations) == 0 {
+				m.notice = "no source locations in failure output"
+				break
+			}
+			m.state = stateLocations
+		case "x":
+			return m.exportQuickfix()
+		case "m", "y":
+			if m.benchRows != nil {
+				m.notice = "Markdown export is only available for test runs"
+				break
+			}
+			md := m.report().markdown()
+			if msg.String() == "y" {
+				m.notice = "copied Markdown summary to clipboard"
+				return m, tea.SetClipboard(md)
+			}
+			return m, cmdExport(m.exportDir, "markdown", "summary.md", md)
 		case "r":
 			m.targets = nil
 			m.cursor = 0
 			m.output = nil
-			return startAsync(m, stateLoading, "Detecting default branch...", loadTargets)
+			return startAsync(m, stateLoading, "Detecting default branch...", loadTargets(m.scope))
 		default:
 			var cmd tea.Cmd
 			m.resultsViewport, cmd = m.resultsViewport.Update(msg)
 			return m, cmd
 		}
+
+	case stateFuzzPick:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = stateBrowse
+		case "up", "k":
+			if m.fuzzCursor > 0 {
+				m.fuzzCursor--
+				ensureCursorVisible(&m.fuzzViewport, m.fuzzCursor)
+			}
+		case "down", "j":
+			if m.fuzzCursor < len(m.fuzzTargets)-1 {
+				m.fuzzCursor++
+				ensureCursorVisible(&m.fuzzViewport, m.fuzzCursor)
+			}
+		case "space":
+			m.fuzzChosen[m.fuzzCursor] = !m.fuzzChosen[m.fuzzCursor]
+		case "enter":
+			return m.startFuzzing()
+		}
+
+	case stateProfilePick:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = stateBrowse
+		case "up", "k":
+			if m.profileCursor > 0 {
+				m.profileCursor--
+				ensureCursorVisible(&m.profileViewport, m.profileCursor)
+			}
+		case "down", "j":
+			if m.profileCursor < len(m.profilePkgs)-1 {
+				m.profileCursor++
+				ensureCursorVisible(&m.profileViewport, m.profileCursor)
+			}
+		case "enter":
+			return m.startProfile(m.profilePkgs[m.profileCursor])
+		}
+
+	case stateFlakePick:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = m.flakeReturn
+		case "up", "k":
+			if m.flakeCursor > 0 {
+				m.flakeCursor--
+				ensureCursorVisible(&m.flakeViewport, m.flakeCursor)
+			}
+		case "down", "j":
+			if m.flakeCursor < len(m.flakeTests)-1 {
+				m.flakeCursor++
+				ensureCursorVisible(&m.flakeViewport, m.flakeCursor)
+			}
+		case "left", "h":
+			m.flakeRuns = max(m.flakeRuns/2, 2)
+		case "right", "l":
+			m.flakeRuns *= 2
+		case "u":
+			m.flakeUntilFail = !m.flakeUntilFail
+		case "enter":
+			test := m.flakeTests[m.flakeCursor]
+			runs := m.flakeRuns
+			if m.flakeUntilFail {
+				runs = 0
+			}
+			m.clearRunMode()
+			m.flake = &flakeTally{test: test, runs: runs}
+			m.output = nil
+			m.runTargets = []string{test.String()}
+			ctx, cancel := context.WithCancel(context.Background())
+			m.stopHunt = cancel
+			return startAsync(m, stateRunning, "Hunting flakes in "+test.String()+"...",
+				huntFlakes(ctx, test, runs))
+		}
+
+	case stateScope:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = stateBrowse
+		case "up", "k":
+			m.scopeCursor = max(m.scopeCursor-1, 0)
+		case "down", "j":
+			m.scopeCursor = min(m.scopeCursor+1, len(scopeKinds)-1)
+		case "left", "h", "-":
+			if scopeKinds[m.scopeCursor] == scopeLastCommits {
+				m.scopeCommits = max(m.scopeCommits-1, 1)
+			}
+		case "right", "l", "+":
+			if scopeKinds[m.scopeCursor] == scopeLastCommits {
+				m.scopeCommits++
+			}
+		case "enter":
+			kind := scopeKinds[m.scopeCursor]
+			if kind == scopeRef {
+				return startAsync(m, stateLoading, "Listing refs...", loadRefs)
+			}
+			return m.setScope(changeScope{kind: kind, commits: m.scopeCommits})
+		}
+
+	case stateScopeRef:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = stateScope
+		case "up", "k":
+			if m.refCursor > 0 {
+				m.refCursor--
+				ensureCursorVisible(&m.refViewport, m.refCursor)
+			}
+		case "down", "j":
+			if m.refCursor < len(m.refs)-1 {
+				m.refCursor++
+				ensureCursorVisible(&m.refViewport, m.refCursor)
+			}
+		case "enter":
+			return m.setScope(changeScope{kind: scopeRef, ref: m.refs[m.refCursor]})
+		}
+
+	case stateLocations:
+		switch msg.String() {
+		case "q", "esc":
+			m.state = stateResults
+		case "up", "k":
+			if m.locCursor > 0 {
+				m.locCursor--
+				ensureCursorVisible(&m.locViewport, m.locCursor)
+			}
+		case "down", "j":
+			if m.locCursor < len(m.locations)-1 {
+				m.locCursor++
+				ensureCursorVisible(&m.locViewport, m.locCursor)
+			}
+		case "enter":
+			l := m.locations[m.locCursor]
+			return m, tea.ExecProcess(editorCommand(l.file, l.line), func(err error) tea.Msg {
+				return editorClosedMsg{err: err}
+			})
+		case "x":
+			m.state = stateResults
+			return m.exportQuickfix()
+		}
 	}
 
 	return m, nil
 }
 
+// setScope switches to scope and rediscovers targets for it.
+func (m Model) setScope(scope changeScope) (Model, tea.Cmd) {
+	m.scope = scope
+	m.targets = nil
+	m.cursor = 0
+	return startAsync(m, stateLoading, "Finding targets for "+scope.String()+"...", loadTargets(scope))
+}
+
+// startFuzzing fuzzes the chosen targets, or the one under the cursor if
+// none are chosen, one after another.
+func (m Model) startFuzzing() (Model, tea.Cmd) {
+	var queue []fuzzTarget
+	for i, t := range m.fuzzTargets {
+		if m.fuzzChosen[i] {
+			queue = append(queue, t)
+		}
+	}
+	if len(queue) == 0 {
+		queue = []fuzzTarget{m.fuzzTargets[m.fuzzCursor]}
+	}
+	m.runTargets = make([]string, len(queue))
+	for i, t := range queue {
+		m.runTargets[i] = t.String()
+	}
+	m.clearRunMode()
+	m.fuzzing = true
+	m.fuzzQueue = queue[1:]
+	m.fuzzOutput = nil
+	m.fuzzCorpus = nil
+	m.output = nil
+	m.exitCode = 0
+	return startAsync(m, stateRunning, "Fuzzing "+queue[0].String()+"...", m.fuzz(queue[0]))
+}
+
+// resultSummary describes the outcome of the last run and whether it
+// succeeded.
+func (m Model) resultSummary() (string, bool) {
+	switch {
+	case m.benchRows != nil:
+		return "Benchmarks finished", true
+	case m.profile != nil:
+		return "Profiled " + m.profile.pkg, m.exitCode == 0
+	case m.flake != nil && m.flake.failed == 0:
+		return fmt.Sprintf("No failures in %d runs", m.flake.done()), true
+	case m.flake != nil:
+		return fmt.Sprintf("Flaky: %d of %d runs failed (%.1f%%)",
+			m.flake.failed, m.flake.done(), m.flake.failureRate()*100), false
+	case m.fuzzing && m.exitCode == 0:
+		return "No fuzz failures", true
+	case m.fuzzing:
+		return "Fuzzing found a failure", false
+	case m.exitCode == 0:
+		return "Tests passed", true
+	default:
+		return "Tests failed", false
+	}
+}
+
+// notifyDone notifies the user that a run finished if it took longer than
+// the configured threshold, since they have likely switched away.
+func (m Model) notifyDone() tea.Cmd {
+	if m.opts.NotifyAfter <= 0 || m.finishedIn < m.opts.NotifyAfter {
+		return nil
+	}
+	summary, _ := m.resultSummary()
+	return notify("rig test-changed", fmt.Sprintf("%s in %s", summary, m.finishedIn.Round(time.Second)), m.opts.NotifySend)
+}
+
+// clearRunMode forgets which kind of run produced the current results.
+func (m *Model) clearRunMode() {
+	m.fuzzing = false
+	m.flake = nil
+	m.profile = nil
+}
+
+// startProfile profiles the tests of a single package.
+func (m Model) startProfile(pkg string) (Model, tea.Cmd) {
+	m.clearRunMode()
+	m.output = nil
+	m.runTargets = []string{pkg}
+	return startAsync(m, stateRunning, "Profiling "+pkg+"...", runProfile(pkg))
+}
+
+// testsInOutput lists the tests in the current output, failing tests first.
+func (m Model) testsInOutput() []outcomeKey {
+	outcomes := collectOutcomes(m.output, m.lineInfo)
+	var tests []outcomeKey
+	for k := range outcomes {
+		if k.name != "" && !outcomes[k].skipped {
+			tests = append(tests, k)
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		fi, fj := outcomes[tests[i]].failed, outcomes[tests[j]].failed
+		if fi != fj {
+			return fi
+		}
+		return tests[i].String() < tests[j].String()
+	})
+	return tests
+}
+
+// pickFlakeTest opens the flake hunt picker; esc returns to from.
+func (m Model) pickFlakeTest(tests []outcomeKey, from viewState) Model {
+	m.state = stateFlakePick
+	m.flakeReturn = from
+	m.flakeTests = tests
+	m.flakeCursor = 0
+	m.flakeViewport.SetYOffset(0)
+	return m
+}
+
+// fuzz streams a fuzz run sized to the results viewport.
+func (m Model) fuzz(t fuzzTarget) tea.Cmd {
+	return streamFuzz(t, m.opts.FuzzTime, m.resultsViewport.Width(), m.resultsViewport.Height(), m.maxOutput)
+}
+
+// exportQuickfix writes the failure locations of the last run to the
+// quickfix file.
+func (m Model) exportQuickfix() (Model, tea.Cmd) {
+	if len(m.locations) == 0 {
+		m.notice = "no source locations in failure output"
+		return m, nil
+	}
+	return m, cmdExport(m.exportDir, "quickfix", "quickfix", quickfix(m.locations))
+}
+
+// exportDoneMsg reports an export written by cmdExport.
+type exportDoneMsg struct {
+	kind string // "quickfix" or "markdown"
+	dir  string // export directory, reused by later exports
+	path string
+	err  error
+}
+
+// cmdExport writes data to the export called name in dir, creating a
+// temporary export directory when dir is empty.
+func cmdExport(dir, kind, name, data string) tea.Cmd {
+	return func() tea.Msg {
+		msg := exportDoneMsg{kind: kind, dir: dir}
+		if msg.dir == "" {
+			d, err := os.MkdirTemp("", "rig-test-changed-*")
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+			msg.dir = d
+		}
+		msg.path = filepath.Join(msg.dir, name)
+		msg.err = os.WriteFile(msg.path, []byte(data), 0o644)
+		return msg
+	}
+}
+
+// report describes the last test run for export.
+func (m Model) report() runReport {
+	return runReport{
+		runner:   m.runnerName,
+		branch:   m.branch,
+		base:     m.base,
+		scope:    m.scope.String(),
+		files:    m.changedFiles,
+		targets:  m.runTargets,
+		snapshot: m.snapshotLabel,
+		passed:   m.exitCode == 0,
+		elapsed:  m.finishedIn,
+		output:   m.output,
+		info:     m.lineInfo,
+	}
+}
+
+// handleSearchKey edits the search query, updating matches as the user types.
+func (m Model) handleSearchKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		m.query = ""
+		m.renderResults()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.query = m.search.Value()
+	m.firstMatchFrom(m.resultsViewport.YOffset())
+	return m, cmd
+}
+
+// showTestResults switches to the results of a finished test run.
+func (m Model) showTestResults(msg testBatchMsg) Model {
+	m.benchRows = nil
+	m.snapshotLabel = msg.snapshot
+	m.state = stateResults
+	m.finishedIn = m.stopwatch.Elapsed()
+	if msg.err != nil {
+		m.exitCode = 1
+	} else {
+		m.exitCode = 0
+	}
+	m.setOutput(msg.lines)
+	m.locations = parseLocations(m.output, m.lineInfo, m.cwd, msg.snapshotDir)
+	m.locCursor = 0
+	m.compareWithPrevious()
+	m.resizeResults()
+	m.resultsViewport.GotoBottom()
+	return m
+}
+
+// runSequence tracks targets run one at a time.
+type runSequence struct {
+	current string
+	queue   []string
+	output  []string
+	total   int
+	failed  int
+	snap    *snapshot // shared by every target when snapshots are on
+}
+
+// sequenceSnapshotMsg carries the snapshot a sequence runs its targets in.
+type sequenceSnapshotMsg struct {
+	snap snapshot
+	err  error
+}
+
+// startSequence runs targets one at a time, ordered by their stats when
+// ordering is enabled.
+func (m Model) startSequence(targets []string) (Model, tea.Cmd) {
+	if m.opts.Ordered {
+		targets = orderTargets(targets, m.stats)
+	}
+	m.seq = &runSequence{current: targets[0], queue: targets[1:], total: len(targets)}
+	if m.opts.Snapshot {
+		return startAsync(m, stateRunning, "Snapshotting working tree...", func() tea.Msg {
+			snap, err := createSnapshot()
+			return sequenceSnapshotMsg{snap: snap, err: err}
+		})
+	}
+	return startAsync(m, stateRunning, m.seq.label(), m.runTests(targets[:1]))
+}
+
+// endSequence clears the sequence, returning a command that removes its
+// snapshot, if any.
+func (m *Model) endSequence() tea.Cmd {
+	seq := m.seq
+	m.seq = nil
+	if seq == nil || seq.snap == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		removeWorktree(seq.snap.dir)
+		return nil
+	}
+}
+
+func (s runSequence) label() string {
+	return fmt.Sprintf("Running %s (%d/%d)...", s.current, s.total-len(s.queue), s.total)
+}
+
+// continueSequence records the target that just finished and starts the
+// next one, or shows the combined results once done or failing fast.
+func (m Model) continueSequence(msg testBatchMsg) (Model, tea.Cmd) {
+	seq := *m.seq
+	save := m.recordStats(runStats([]string{seq.current}, msg, time.Now()))
+
+	seq.output = append(seq.output, "=== TARGET "+seq.current)
+	seq.output = append(seq.output, msg.lines...)
+	if msg.err != nil {
+		seq.failed++
+		if m.opts.FailFast && len(seq.queue) > 0 {
+			seq.output = append(seq.output, "", fmt.Sprintf("fail-fast: skipped %d remaining target(s): %s",
+				len(seq.queue), strings.Join(seq.queue, " ")))
+			seq.queue = nil
+		}
+	}
+
+	if len(seq.queue) > 0 {
+		seq.current, seq.queue = seq.queue[0], seq.queue[1:]
+		m.seq = &seq
+		m.loadingMsg = seq.label()
+		m.output = nil
+		return m, tea.Batch(save, m.runTests([]string{seq.current}))
+	}
+
+	cleanup := m.endSequence()
+	var err error
+	if seq.failed > 0 {
+		err = fmt.Errorf("%d of %d target(s) failed", seq.failed, seq.total)
+	}
+	m = m.showTestResults(testBatchMsg{lines: seq.output, snapshot: msg.snapshot, snapshotDir: msg.snapshotDir, err: err})
+	return m, tea.Batch(save, cleanup, m.notifyDone())
+}
+
+// recordStats merges the outcome of a run into the stats and returns a
+// command saving them.
+func (m *Model) recordStats(run targetStats) tea.Cmd {
+	if len(run) == 0 {
+		return nil
+	}
+	stats := maps.Clone(m.stats)
+	if stats == nil {
+		stats = make(targetStats, len(run))
+	}
+	maps.Copy(stats, run)
+	m.stats = stats
+	return saveStats(maps.Clone(stats))
+}
+
+// compareWithPrevious compares the outcomes of the run that just finished
+// with the previous run of the same targets and records them for the next.
+func (m *Model) compareWithPrevious() {
+	m.comparison = nil
+	cur := collectOutcomes(m.output, m.lineInfo)
+	if len(cur) == 0 {
+		return
+	}
+	if prev, ok := m.history[m.runKey]; ok {
+		c := compareRuns(prev, cur)
+		m.comparison = &c
+	}
+	m.history[m.runKey] = cur
+}
+
+// resizeResults fits the results viewport below the header, which grows
+// when there is a comparison with the previous run.
+func (m *Model) resizeResults() {
+	if m.height == 0 {
+		return
+	}
+	// header(2 lines) + border/padding(4) + help+scroll(3)
+	h := m.height - 9
+	if m.comparison != nil {
+		h -= len(m.comparison.render())
+	}
+	if m.profile != nil {
+		h-- // profile location
+	}
+	m.resultsViewport.SetHeight(max(h, 1))
+}
+
+// runTests streams a test run sized to the results viewport, in the
+// sequence's snapshot when there is one.
+func (m Model) runTests(targets []string) tea.Cmd {
+	var snap *snapshot
+	if m.seq != nil {
+		snap = m.seq.snap
+	}
+	return streamLines(m.runnerName, targets, snap, m.opts.Snapshot,
+		m.resultsViewport.Width(), m.resultsViewport.Height(), m.maxOutput)
+}
+
+// selectedTargets returns the targets under the cursor, expanding the
+// synthetic "All" entry to every real target.
+func (m Model) selectedTargets() []string {
+	if m.cursor != 0 {
+		return []string{m.targets[m.cursor].target}
+	}
+	targets := make([]string, 0, len(m.targets)-1)
+	for _, t := range m.targets[1:] {
+		targets = append(targets, t.target)
+	}
+	return targets
+}
+
 // browseViewportLine maps a cursor index to a viewport line,
 // accounting for the blank line after the "All" entry at index 0.
 func browseViewportLine(cursor int) int {
@@ -389,26 +1401,23 @@ func ensureCursorVisible(vp *viewport.Model, cursor int) {
 
 const tailLines = 30
 
-func colorizeOutput(lines []string) string {
-	var b strings.Builder
-	for i, line := range lines {
-		switch {
-		case strings.HasPrefix(line, "ok"):
-			b.WriteString(styles.Success.Render(line))
-		case strings.HasPrefix(line, "FAIL"):
-			b.WriteString(styles.Err.Render(line))
-		case strings.Contains(line, "--- PASS"):
-			b.WriteString(styles.Success.Render(line))
-		case strings.Contains(line, "--- FAIL"):
-			b.WriteString(styles.Err.Render(line))
-		default:
-			b.WriteString(line)
-		}
-		if i < len(lines)-1 {
-			b.WriteByte('\n')
-		}
+// colorizeLine highlights pass/fail lines. Lines the runner already
+// colored are left untouched.
+func colorizeLine(line string) string {
+	switch {
+	case ansi.Strip(line) != line:
+		return line
+	case strings.HasPrefix(line, "ok"):
+		return styles.Success.Render(line)
+	case strings.HasPrefix(line, "FAIL"):
+		return styles.Err.Render(line)
+	case strings.Contains(line, "--- PASS"):
+		return styles.Success.Render(line)
+	case strings.Contains(line, "--- FAIL"):
+		return styles.Err.Render(line)
+	default:
+		return line
 	}
-	return b.String()
 }
 
 func (m Model) View() tea.View {
@@ -434,14 +1443,24 @@ func (m Model) View() tea.View {
 		content = styles.Title.Render("Test Changed Files") + "\n\n"
 
 		if len(m.targets) == 0 {
-			content += styles.Dimmed.Render("No affected test targets found.") + "\n"
+			content += styles.Dimmed.Render("No affected test targets found in "+m.scope.String()+".") + "\n"
 			content += "\n" + m.help.View(browseEmptyKeys)
 		} else {
 			// Subtract 1 for the synthetic "All" entry.
 			realCount := len(m.targets) - 1
 			content += styles.Subtitle.Render(
-				fmt.Sprintf("Found %d target(s) via %s runner:", realCount, m.runnerName),
-			) + "\n\n"
+				fmt.Sprintf("Found %d target(s) in %s via %s runner:", realCount, m.scope, m.runnerName),
+			)
+			if m.opts.Snapshot {
+				content += "  " + styles.Selected.Render("[snapshot]")
+			}
+			if m.opts.Ordered {
+				content += "  " + styles.Selected.Render("[failed/slowest first]")
+			}
+			if m.opts.FailFast {
+				content += "  " + styles.Selected.Render("[fail-fast]")
+			}
+			content += "\n\n"
 
 			var listContent strings.Builder
 			for i, t := range m.targets {
@@ -477,6 +1496,20 @@ func (m Model) View() tea.View {
 		content = m.spinner.View() + " " + styles.Dimmed.Render(m.loadingMsg) +
 			"  " + styles.Subtitle.Render(elapsed) + "\n\n"
 
+		if m.flake != nil {
+			content += styles.Subtitle.Render(m.flake.summary()) + "\n"
+			if m.stopHunt != nil {
+				content += styles.Dimmed.Render("esc to stop") + "\n"
+			}
+			content += "\n"
+		}
+		if m.fuzzing {
+			if p, ok := parseFuzzProgress(m.output); ok {
+				content += styles.Subtitle.Render(fmt.Sprintf("execs %s  %s/sec  interesting %s  (%s)",
+					p.execs, p.perSec, p.interesting, p.elapsed)) + "\n\n"
+			}
+		}
+
 		// Show tail of output collected so far.
 		if len(m.output) > 0 {
 			start := max(len(m.output)-tailLines, 0)
@@ -487,23 +1520,201 @@ func (m Model) View() tea.View {
 
 	case stateResults:
 		elapsed := fmt.Sprintf("%.2fs", m.finishedIn.Seconds())
-		if m.exitCode == 0 {
-			content = styles.Success.Render("✓ Tests passed") + "  " +
-				styles.Subtitle.Render(elapsed) + "\n\n"
-		} else {
-			content = styles.Err.Render("✗ Tests failed") + "  " +
+		switch {
+		case m.benchRows != nil:
+			content = styles.Title.Render("Benchmarks") + "  " +
+				styles.Dimmed.Render(shortSHA(m.base)+" → working tree") + "  " +
 				styles.Subtitle.Render(elapsed) + "\n\n"
+		case m.profile != nil:
+			content = styles.Title.Render("Profile") + "  " +
+				styles.Dimmed.Render(m.profile.pkg) + "  " +
+				styles.Subtitle.Render(elapsed) + "\n" +
+				styles.Dimmed.Render("profiles kept in "+m.profile.dir)
+		default:
+			summary, ok := m.resultSummary()
+			if ok {
+				content = styles.Success.Render("✓ " + summary)
+			} else {
+				content = styles.Err.Render("✗ " + summary)
+			}
+			content += "  " + styles.Subtitle.Render(elapsed)
+		}
+		if m.benchRows == nil {
+			if m.snapshotLabel != "" {
+				content += "  " + styles.Dimmed.Render("snapshot "+m.snapshotLabel)
+			}
+			if m.comparison != nil {
+				content += "\n" + strings.Join(m.comparison.render(), "\n")
+			}
+			content += "\n\n"
 		}
 
 		content += m.resultsViewport.View()
 
-		if len(m.output) > m.resultsViewport.Height() {
-			content += "\n" + styles.Dimmed.Render(
+		var status []string
+		if len(m.visible) > m.resultsViewport.Height() {
+			status = append(status, styles.Dimmed.Render(
 				fmt.Sprintf("(%d%% — ↑↓/jk to scroll)", int(m.resultsViewport.ScrollPercent()*100)),
-			)
+			))
+		}
+		if s := m.resultsStatus(); s != "" {
+			status = append(status, styles.Subtitle.Render(s))
+		}
+		if m.notice != "" {
+			status = append(status, styles.Selected.Render(m.notice))
+		}
+		if len(status) > 0 {
+			content += "\n" + strings.Join(status, "  ")
+		}
+
+		if m.searching {
+			content += "\n" + m.search.View() + "  " + m.help.View(searchKeys)
+		} else {
+			content += "\n" + m.help.View(resultsKeys)
 		}
 
-		content += "\n" + m.help.View(resultsKeys)
+	case stateProfilePick:
+		content = styles.Title.Render("Profile") + "\n\n"
+		content += styles.Subtitle.Render(
+			fmt.Sprintf("%d package(s); profiling needs exactly one:", len(m.profilePkgs)),
+		) + "\n\n"
+
+		var listContent strings.Builder
+		for i, pkg := range m.profilePkgs {
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.profileCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			listContent.WriteString(cursor + nameStyle.Render(pkg))
+			if i < len(m.profilePkgs)-1 {
+				listContent.WriteByte('\n')
+			}
+		}
+		m.profileViewport.SetContent(listContent.String())
+		content += m.profileViewport.View()
+		content += "\n" + m.help.View(profilePickKeys)
+
+	case stateFlakePick:
+		content = styles.Title.Render("Flake Hunt") + "\n\n"
+		plan := fmt.Sprintf("Run a test %d times in parallel:", m.flakeRuns)
+		if m.flakeUntilFail {
+			plan = "Run a test in parallel until it fails:"
+		}
+		content += styles.Subtitle.Render(plan) + "\n\n"
+
+		var listContent strings.Builder
+		for i, t := range m.flakeTests {
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.flakeCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			listContent.WriteString(cursor + nameStyle.Render(t.name) + "  " + styles.Remote.Render(t.pkg))
+			if i < len(m.flakeTests)-1 {
+				listContent.WriteByte('\n')
+			}
+		}
+		m.flakeViewport.SetContent(listContent.String())
+		content += m.flakeViewport.View()
+		content += "\n" + m.help.View(flakePickKeys)
+
+	case stateScope:
+		content = styles.Title.Render("Change Scope") + "\n\n"
+		content += styles.Subtitle.Render("Find targets affected by:") + "\n\n"
+		for i, kind := range scopeKinds {
+			label := changeScope{kind: kind, commits: m.scopeCommits, ref: "ref..."}.String()
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.scopeCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			content += cursor + nameStyle.Render(label)
+			if kind == m.scope.kind {
+				content += "  " + styles.Remote.Render("(current)")
+			}
+			content += "\n"
+		}
+		content += "\n" + m.help.View(scopeKeys)
+
+	case stateScopeRef:
+		content = styles.Title.Render("Compare Against") + "\n\n"
+		content += styles.Subtitle.Render(fmt.Sprintf("%d ref(s), most recent first:", len(m.refs))) + "\n\n"
+
+		var listContent strings.Builder
+		for i, ref := range m.refs {
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.refCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			listContent.WriteString(cursor + nameStyle.Render(ref))
+			if i < len(m.refs)-1 {
+				listContent.WriteByte('\n')
+			}
+		}
+		m.refViewport.SetContent(listContent.String())
+		content += m.refViewport.View()
+		content += "\n" + m.help.View(refKeys)
+
+	case stateFuzzPick:
+		content = styles.Title.Render("Fuzz Targets") + "\n\n"
+		content += styles.Subtitle.Render(
+			fmt.Sprintf("%d fuzz target(s), %s each:", len(m.fuzzTargets), m.opts.FuzzTime),
+		) + "\n\n"
+
+		var listContent strings.Builder
+		for i, t := range m.fuzzTargets {
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.fuzzCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			check := "[ ] "
+			if m.fuzzChosen[i] {
+				check = "[x] "
+			}
+			listContent.WriteString(cursor + nameStyle.Render(check+t.name) + "  " + styles.Remote.Render(t.pkg))
+			if i < len(m.fuzzTargets)-1 {
+				listContent.WriteByte('\n')
+			}
+		}
+		m.fuzzViewport.SetContent(listContent.String())
+		content += m.fuzzViewport.View()
+		content += "\n" + m.help.View(fuzzPickKeys)
+
+	case stateLocations:
+		content = styles.Title.Render("Failure Locations") + "\n\n"
+		content += styles.Subtitle.Render(
+			fmt.Sprintf("%d location(s) in failure output:", len(m.locations)),
+		) + "\n\n"
+
+		var listContent strings.Builder
+		for i, l := range m.locations {
+			cursor := "  "
+			nameStyle := styles.Dimmed
+			if i == m.locCursor {
+				cursor = styles.Selected.Render("> ")
+				nameStyle = styles.Selected
+			}
+			file := l.file
+			if rel, err := filepath.Rel(m.cwd, file); err == nil {
+				file = rel
+			}
+			listContent.WriteString(cursor + nameStyle.Render(fmt.Sprintf("%s:%d", file, l.line)) +
+				"  " + styles.Remote.Render(l.msg))
+			if i < len(m.locations)-1 {
+				listContent.WriteByte('\n')
+			}
+		}
+		m.locViewport.SetContent(listContent.String())
+		content += m.locViewport.View()
+		content += "\n" + m.help.View(locationKeys)
 	}
 
 	return tea.NewView(styles.Box.Render(content))
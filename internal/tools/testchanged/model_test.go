@@ -0,0 +1,160 @@
+package testchanged
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/messages"
+)
+
+// Key helpers to keep tests readable.
+func keyRune(r rune) tea.KeyPressMsg    { return tea.KeyPressMsg{Code: r, Text: string(r)} }
+func keyCode(code rune) tea.KeyPressMsg { return tea.KeyPressMsg{Code: code} }
+
+// modelWithResults returns a Model in stateResults showing goTestOutput.
+func modelWithResults() Model {
+	m := New()
+	m.state = stateResults
+	m.exitCode = 1
+	m.setOutput(goTestOutput)
+	return m
+}
+
+func press(m Model, keys ...tea.KeyPressMsg) Model {
+	for _, k := range keys {
+		r, _ := m.Update(k)
+		m = r.(Model)
+	}
+	return m
+}
+
+// ---------------------------------------------------------------------------
+// Results: filter
+// ---------------------------------------------------------------------------
+
+func TestResults_FilterCycles(t *testing.T) {
+	m := modelWithResults()
+
+	m = press(m, keyRune('f'))
+	if m.filterLabel() != "failures" {
+		t.Fatalf("expected failures filter, got %q", m.filterLabel())
+	}
+	for _, i := range m.visible {
+		if !m.lineInfo[i].failure {
+			t.Errorf("non-failure line %q visible under failures filter", m.output[i])
+		}
+	}
+
+	m = press(m, keyRune('f'))
+	if m.filterLabel() != "panics" {
+		t.Fatalf("expected panics filter, got %q", m.filterLabel())
+	}
+
+	m = press(m, keyRune('f'))
+	if m.filterLabel() != "example.com/calc" {
+		t.Fatalf("expected first package filter, got %q", m.filterLabel())
+	}
+	if len(m.visible) != 11 {
+		t.Errorf("expected 11 lines for example.com/calc, got %d", len(m.visible))
+	}
+
+	// Cycle through the remaining packages back to all.
+	m = press(m, keyRune('f'), keyRune('f'), keyRune('f'), keyRune('f'))
+	if m.filter != filterAll || len(m.visible) != len(goTestOutput) {
+		t.Errorf("expected to wrap back to all lines, got filter %d with %d lines", m.filter, len(m.visible))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Results: search
+// ---------------------------------------------------------------------------
+
+func TestResults_SearchAndNavigate(t *testing.T) {
+	m := modelWithResults()
+
+	m = press(m, keyRune('/'))
+	if !m.searching {
+		t.Fatal("expected / to start searching")
+	}
+	m = press(m, keyRune('f'), keyRune('a'), keyRune('i'), keyRune('l'), keyCode(tea.KeyEnter))
+	if m.searching {
+		t.Error("expected enter to finish searching")
+	}
+	if m.query != "fail" {
+		t.Errorf("expected query 'fail', got %q", m.query)
+	}
+	if len(m.matches) != 6 {
+		t.Fatalf("expected 6 matching lines, got %d", len(m.matches))
+	}
+
+	m = press(m, keyRune('n'))
+	if m.matchIdx != 1 {
+		t.Errorf("expected matchIdx=1 after n, got %d", m.matchIdx)
+	}
+	m = press(m, keyRune('N'), keyRune('N'))
+	if m.matchIdx != len(m.matches)-1 {
+		t.Errorf("expected N to wrap to last match, got %d", m.matchIdx)
+	}
+}
+
+func TestResults_FirstMatchUsesNewQuery(t *testing.T) {
+	m := modelWithResults()
+	m.query = "fail"
+	m.renderResults()
+
+	// Matches for "boom" are rows 11, 12 and 14; those for "fail" differ.
+	m.query = "boom"
+	m.firstMatchFrom(12)
+	if !reflect.DeepEqual(m.matches, []int{11, 12, 14}) || m.matchIdx != 1 {
+		t.Errorf("matches %v, matchIdx %d, want the match on row 12", m.matches, m.matchIdx)
+	}
+}
+
+func TestResults_EscClearsBeforeLeaving(t *testing.T) {
+	m := modelWithResults()
+	m.query = "boom"
+	m.filter = filterFailures
+	m.renderResults()
+
+	r, cmd := m.Update(keyCode(tea.KeyEscape))
+	m = r.(Model)
+	if cmd != nil {
+		t.Error("expected first esc to clear, not leave")
+	}
+	if m.query != "" || m.filter != filterAll {
+		t.Errorf("expected search and filter cleared, got %q / %d", m.query, m.filter)
+	}
+
+	_, cmd = m.Update(keyCode(tea.KeyEscape))
+	if cmd == nil {
+		t.Fatal("expected second esc to leave")
+	}
+	if _, ok := cmd().(messages.BackMsg); !ok {
+		t.Error("expected BackMsg")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Results: failure jumps
+// ---------------------------------------------------------------------------
+
+func TestResults_JumpFailure(t *testing.T) {
+	m := modelWithResults()
+
+	want := []int{2, 6, 9, 12, 17}
+	if len(m.failureStarts) != len(want) {
+		t.Fatalf("failureStarts = %v, want %v", m.failureStarts, want)
+	}
+	for _, row := range want {
+		m = press(m, keyRune(']'))
+		if m.failureRow != row {
+			t.Errorf("expected ] to jump to row %d, got %d", row, m.failureRow)
+		}
+	}
+	m = press(m, keyRune('['))
+	if m.failureRow != 12 {
+		t.Errorf("expected [ to jump back to row 12, got %d", m.failureRow)
+	}
+}
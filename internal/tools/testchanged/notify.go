@@ -0,0 +1,41 @@
+package testchanged
+
+import (
+	"os/exec"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// notify announces title and body through every channel a terminal might
+// support: OSC 9 (iTerm2, WezTerm, Windows Terminal, Ghostty), OSC 777
+// (urxvt, foot, Ghostty) and the bell, which most terminals turn into an
+// urgency hint. Terminals ignore sequences they don't understand. With
+// desktop set, notify-send is used too when it is installed.
+func notify(title, body string, desktop bool) tea.Cmd {
+	title, body = oscSafe(title), oscSafe(body)
+	seq := "\x1b]9;" + title + ": " + body + "\a" +
+		"\x1b]777;notify;" + title + ";" + body + "\a" +
+		"\a"
+	cmds := []tea.Cmd{tea.Raw(seq)}
+	if desktop {
+		if path, err := exec.LookPath("notify-send"); err == nil {
+			cmds = append(cmds, func() tea.Msg {
+				_ = exec.Command(path, "--app-name=rig", title, body).Run()
+				return nil
+			})
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// oscSafe strips characters that would end an OSC sequence early or split
+// OSC 777 fields.
+func oscSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == ';' {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,42 @@
+package testchanged
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOSCSafe(t *testing.T) {
+	if got, want := oscSafe("a;b\x07c\x1b]d\ne"), "abc]de"; got != want {
+		t.Errorf("oscSafe = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyDone_Threshold(t *testing.T) {
+	m := modelWithResults()
+
+	m.finishedIn = 5 * time.Second
+	if m.notifyDone() != nil {
+		t.Error("expected no notification for a quick run")
+	}
+
+	m.finishedIn = time.Minute
+	if m.notifyDone() == nil {
+		t.Error("expected a notification for a slow run")
+	}
+
+	m.opts.NotifyAfter = 0
+	if m.notifyDone() != nil {
+		t.Error("expected notifications to be disabled")
+	}
+}
+
+func TestResultSummary(t *testing.T) {
+	m := modelWithResults()
+	if s, ok := m.resultSummary(); s != "Tests failed" || ok {
+		t.Errorf("resultSummary = %q, %v", s, ok)
+	}
+	m.flake = &flakeTally{runs: 10, passed: 10}
+	if s, ok := m.resultSummary(); s != "No failures in 10 runs" || !ok {
+		t.Errorf("resultSummary = %q, %v", s, ok)
+	}
+}
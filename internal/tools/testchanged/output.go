@@ -0,0 +1,169 @@
+package testchanged
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// lineInfo describes one line of test output.
+type lineInfo struct {
+	pkg     string // package or target the line belongs to, if known
+	failure bool   // part of a failing test, build error or failing summary
+	panic   bool   // part of a panic trace
+}
+
+// packageResult is a per-package summary line, e.g. "ok  pkg 0.12s".
+type packageResult struct {
+	pkg      string
+	passed   bool
+	skipped  bool // no test files
+	duration time.Duration
+}
+
+// testResult is a per-test result line, e.g. "--- FAIL: TestFoo (0.01s)".
+type testResult struct {
+	name     string
+	status   string // PASS, FAIL or SKIP
+	duration time.Duration
+}
+
+// parsePackageResult recognises go test package summaries and Bazel target
+// summaries.
+func parsePackageResult(line string) (packageResult, bool) {
+	fields := strings.Fields(ansi.Strip(line))
+	if len(fields) < 2 {
+		return packageResult{}, false
+	}
+
+	if strings.HasPrefix(fields[0], "//") || strings.HasPrefix(fields[0], "@") {
+		// //pkg:target  (cached) PASSED in 0.4s
+		r := packageResult{pkg: fields[0]}
+		rest := fields[1:]
+		if rest[0] == "(cached)" {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return packageResult{}, false
+		}
+		switch rest[0] {
+		case "PASSED":
+			r.passed = true
+		case "FAILED", "TIMEOUT", "FLAKY", "INCOMPLETE":
+		default:
+			return packageResult{}, false
+		}
+		if len(rest) >= 3 && rest[1] == "in" {
+			r.duration, _ = time.ParseDuration(rest[2])
+		}
+		return r, true
+	}
+
+	r := packageResult{pkg: fields[1]}
+	switch fields[0] {
+	case "ok":
+		r.passed = true
+	case "FAIL":
+	case "?":
+		r.passed = true
+		r.skipped = true
+	default:
+		return packageResult{}, false
+	}
+	if len(fields) >= 3 {
+		r.duration, _ = time.ParseDuration(fields[2])
+	}
+	return r, true
+}
+
+// parseTestResult recognises go test per-test result lines, including
+// indented subtest results.
+func parseTestResult(line string) (testResult, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(ansi.Strip(line)), "--- ")
+	if !ok {
+		return testResult{}, false
+	}
+	status, rest, ok := strings.Cut(rest, ": ")
+	if !ok || (status != "PASS" && status != "FAIL" && status != "SKIP") {
+		return testResult{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return testResult{}, false
+	}
+	r := testResult{name: fields[0], status: status}
+	if len(fields) >= 2 {
+		r.duration, _ = time.ParseDuration(strings.Trim(fields[1], "()"))
+	}
+	return r, true
+}
+
+// analyzeOutput attributes each line of go test -v output to its package
+// and marks lines belonging to failing tests, build errors and panics.
+// Package output is contiguous and ends with its summary line, so lines are
+// attributed to the next summary that follows them.
+func analyzeOutput(lines []string) []lineInfo {
+	info := make([]lineInfo, len(lines))
+	owner := make([]string, len(lines)) // test that produced each line
+	failed := make(map[string]bool)
+	current := ""
+	start := 0
+	inPanic, inBuild := false, false
+
+	markFailedTests := func(end int) {
+		for j := start; j < end; j++ {
+			if failed[owner[j]] {
+				info[j].failure = true
+			}
+		}
+	}
+
+	for i, raw := range lines {
+		line := ansi.Strip(raw)
+		trimmed := strings.TrimSpace(line)
+
+		if f := strings.Fields(line); len(f) >= 3 && f[0] == "===" &&
+			(f[1] == "RUN" || f[1] == "CONT" || f[1] == "PAUSE" || f[1] == "NAME") {
+			current = f[2]
+		}
+		owner[i] = current
+		if r, ok := parseTestResult(line); ok {
+			owner[i] = r.name
+			current = r.name
+			if r.status == "FAIL" {
+				failed[r.name] = true
+			}
+		}
+
+		if strings.HasPrefix(trimmed, "panic: ") {
+			inPanic = true
+		}
+		if strings.HasPrefix(line, "# ") {
+			inBuild = true
+		}
+		info[i].panic = inPanic
+		info[i].failure = inPanic || inBuild || trimmed == "FAIL"
+
+		r, ok := parsePackageResult(line)
+		if !ok {
+			continue
+		}
+		info[i].pkg = r.pkg
+		info[i].failure = info[i].failure || !r.passed
+		if strings.HasPrefix(r.pkg, "//") || strings.HasPrefix(r.pkg, "@") {
+			// Bazel prints target summaries together at the end.
+			continue
+		}
+		for j := start; j < i; j++ {
+			info[j].pkg = r.pkg
+		}
+		markFailedTests(i)
+		start = i + 1
+		failed = make(map[string]bool)
+		current = ""
+		inPanic, inBuild = false, false
+	}
+	markFailedTests(len(lines))
+	return info
+}
@@ -0,0 +1,94 @@
+package testchanged
+
+import (
+	"testing"
+	"time"
+)
+
+// goTestOutput is go test -v output for two packages, one failing with a
+// subtest failure and one panicking, as rendered by termBuffer.
+var goTestOutput = []string{
+	"=== RUN   TestAdd",                               // 0
+	"--- PASS: TestAdd (0.00s)",                       // 1
+	"=== RUN   TestSub",                               // 2
+	"=== RUN   TestSub/neg",                           // 3
+	"    sub_test.go:12: got 1, want -1",              // 4
+	"=== RUN   TestSub/pos",                           // 5
+	"--- FAIL: TestSub (0.01s)",                       // 6
+	"    --- FAIL: TestSub/neg (0.00s)",               // 7
+	"    --- PASS: TestSub/pos (0.00s)",               // 8
+	"FAIL",                                            // 9
+	"FAIL    example.com/calc        0.012s",          // 10
+	"=== RUN   TestBoom",                              // 11
+	"panic: boom",                                     // 12
+	"goroutine 7 [running]:",                          // 13
+	"FAIL    example.com/boom        0.004s",          // 14
+	"ok      example.com/fine        0.100s",          // 15
+	"?       example.com/none        [no test files]", // 16
+	"FAIL", // 17
+}
+
+func TestAnalyzeOutput(t *testing.T) {
+	info := analyzeOutput(goTestOutput)
+
+	wantPkg := map[int]string{0: "example.com/calc", 10: "example.com/calc", 12: "example.com/boom", 15: "example.com/fine", 16: "example.com/none", 17: ""}
+	for i, want := range wantPkg {
+		if info[i].pkg != want {
+			t.Errorf("line %d pkg = %q, want %q", i, info[i].pkg, want)
+		}
+	}
+
+	failing := map[int]bool{2: true, 3: true, 4: true, 6: true, 7: true, 9: true, 10: true, 12: true, 13: true, 14: true, 17: true}
+	for i := range goTestOutput {
+		if info[i].failure != failing[i] {
+			t.Errorf("line %d (%q) failure = %v, want %v", i, goTestOutput[i], info[i].failure, failing[i])
+		}
+	}
+
+	for i := range goTestOutput {
+		wantPanic := i >= 12 && i <= 14
+		if info[i].panic != wantPanic {
+			t.Errorf("line %d panic = %v, want %v", i, info[i].panic, wantPanic)
+		}
+	}
+}
+
+func TestParsePackageResult(t *testing.T) {
+	tests := []struct {
+		line string
+		want packageResult
+		ok   bool
+	}{
+		{"ok      example.com/a   0.25s", packageResult{pkg: "example.com/a", passed: true, duration: 250 * time.Millisecond}, true},
+		{"ok      example.com/a   (cached)", packageResult{pkg: "example.com/a", passed: true}, true},
+		{"FAIL    example.com/b [build failed]", packageResult{pkg: "example.com/b"}, true},
+		{"?       example.com/c   [no test files]", packageResult{pkg: "example.com/c", passed: true, skipped: true}, true},
+		{"//foo:bar_test    (cached) PASSED in 1.5s", packageResult{pkg: "//foo:bar_test", passed: true, duration: 1500 * time.Millisecond}, true},
+		{"//foo:baz_test    FAILED in 0.3s", packageResult{pkg: "//foo:baz_test", duration: 300 * time.Millisecond}, true},
+		{"FAIL", packageResult{}, false},
+		{"okay then", packageResult{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePackageResult(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parsePackageResult(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		line, query string
+		want        int
+	}{
+		{"FAIL foo fail", "fail", 2},
+		{"FAIL foo fail", "FAIL", 1},
+		{"nothing here", "fail", 0},
+		{"anything", "", 0},
+	}
+	for _, tt := range tests {
+		if got := findMatches(tt.line, tt.query); len(got) != tt.want {
+			t.Errorf("findMatches(%q, %q) = %v, want %d matches", tt.line, tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package testchanged
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// profileTopN is how many functions each pprof table shows.
+const profileTopN = 30
+
+type packagesListedMsg struct {
+	pkgs []string
+	err  error
+}
+
+// profileDoneMsg carries the pprof tables for a profiled package. dir holds
+// the profiles and test binary, which are kept for later inspection.
+type profileDoneMsg struct {
+	pkg   string
+	dir   string
+	lines []string
+	err   error
+}
+
+// listPackages expands targets into import paths.
+func listPackages(targets []string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := exec.Command("go", append([]string{"list"}, targets...)...).CombinedOutput()
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if err != nil {
+			return packagesListedMsg{err: fmt.Errorf("go list: %s", tail(lines, 10))}
+		}
+		return packagesListedMsg{pkgs: lines}
+	}
+}
+
+// RunProfile runs pkg's tests writing CPU and memory profiles, and the test
+// binary they refer to, into dir. Profiling only works for one package.
+func (GoRunner) RunProfile(pkg, dir string) *exec.Cmd {
+	return exec.Command("go", "test",
+		"-cpuprofile", filepath.Join(dir, "cpu.pprof"),
+		"-memprofile", filepath.Join(dir, "mem.pprof"),
+		"-o", filepath.Join(dir, path.Base(pkg)+".test"),
+		pkg)
+}
+
+// newProfileDir creates a directory for a profile of pkg under the user
+// cache directory, named so that later profiles sort after earlier ones.
+func newProfileDir(pkg string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(pkg) + "-" + time.Now().Format("20060102-150405")
+	dir := filepath.Join(cache, "rig", "test-changed", "profiles", name)
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+// runProfile profiles pkg's tests and summarises both profiles with
+// go tool pprof -top.
+func runProfile(pkg string) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := newProfileDir(pkg)
+		if err != nil {
+			return profileDoneMsg{err: fmt.Errorf("profile dir: %w", err)}
+		}
+
+		out, testErr := GoRunner{}.RunProfile(pkg, dir).CombinedOutput()
+		testLines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+
+		var lines []string
+		if testErr != nil {
+			lines = append(lines, "Tests failed; profiles cover the run up to the failure.", "")
+			lines = append(lines, testLines...)
+		} else {
+			lines = append(lines, tail(testLines, 1))
+		}
+
+		for _, p := range []struct {
+			title, file string
+			args        []string
+		}{
+			{"CPU", "cpu.pprof", nil},
+			{"Memory (allocated bytes)", "mem.pprof", []string{"-sample_index=alloc_space"}},
+		} {
+			lines = append(lines, "", "=== "+p.title)
+			top, err := pprofTop(filepath.Join(dir, p.file), p.args...)
+			if err != nil {
+				lines = append(lines, err.Error())
+				continue
+			}
+			lines = append(lines, top...)
+		}
+		return profileDoneMsg{pkg: pkg, dir: dir, lines: lines, err: testErr}
+	}
+}
+
+// pprofTop returns the text-mode top table for a profile.
+func pprofTop(profile string, args ...string) ([]string, error) {
+	if _, err := os.Stat(profile); err != nil {
+		return nil, fmt.Errorf("no profile written: %s", filepath.Base(profile))
+	}
+	args = append([]string{"tool", "pprof", "-top", "-nodecount=" + strconv.Itoa(profileTopN)}, args...)
+	cmd := exec.Command("go", append(args, profile)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pprof: %s", strings.TrimSpace(stderr.String()))
+	}
+	return strings.Split(strings.TrimRight(string(out), "\n"), "\n"), nil
+}
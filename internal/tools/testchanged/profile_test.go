@@ -0,0 +1,74 @@
+package testchanged
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestProfile_PicksPackageWhenTargetHasSeveral(t *testing.T) {
+	m := modelWithTargets("./...")
+
+	r, _ := m.Update(packagesListedMsg{pkgs: []string{"example.com/a", "example.com/b"}})
+	m = r.(Model)
+	if m.state != stateProfilePick {
+		t.Fatalf("expected package picker, got state %d", m.state)
+	}
+
+	m = press(m, keyRune('j'))
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateRunning || cmd == nil || m.loadingMsg != "Profiling example.com/b..." {
+		t.Fatalf("expected example.com/b to be profiled, got state %d %q", m.state, m.loadingMsg)
+	}
+}
+
+func TestProfile_SinglePackageStartsImmediately(t *testing.T) {
+	m := modelWithTargets("./a/...")
+
+	r, _ := m.Update(packagesListedMsg{pkgs: []string{"example.com/a"}})
+	m = r.(Model)
+	if m.state != stateRunning {
+		t.Fatalf("expected profiling to start, got state %d", m.state)
+	}
+}
+
+func TestProfile_Results(t *testing.T) {
+	m := modelWithTargets("./a/...")
+	lines := []string{"--- FAIL: TestA (0.00s)", "FAIL", "", "=== CPU", "      flat  flat%   sum%        cum   cum%"}
+
+	r, _ := m.Update(profileDoneMsg{pkg: "example.com/a", dir: "/cache/p", lines: lines, err: errors.New("exit status 1")})
+	m = r.(Model)
+	if m.state != stateResults || m.exitCode != 1 || m.profile == nil {
+		t.Fatalf("expected profile results, got state %d exit %d", m.state, m.exitCode)
+	}
+	if view := m.View().Content; !strings.Contains(view, "profiles kept in /cache/p") {
+		t.Errorf("view missing profile location:\n%s", view)
+	}
+
+	// Any other run clears the profile mode.
+	m.state = stateBrowse
+	m = press(m, keyCode(tea.KeyEnter))
+	if m.profile != nil {
+		t.Error("expected a test run to clear the profile")
+	}
+}
+
+func TestProfile_NoDirShowsError(t *testing.T) {
+	m := modelWithTargets("./a/...")
+
+	r, _ := m.Update(profileDoneMsg{pkg: "example.com/a", err: errors.New("profile dir: permission denied")})
+	m = r.(Model)
+	if m.state == stateResults || m.profile != nil || !strings.Contains(m.errSplash, "permission denied") {
+		t.Fatalf("expected an error, got state %d, error %q", m.state, m.errSplash)
+	}
+}
+
+func TestPprofTopMissingProfile(t *testing.T) {
+	if _, err := pprofTop(filepath.Join(t.TempDir(), "cpu.pprof")); err == nil || !strings.Contains(err.Error(), "no profile written") {
+		t.Errorf("err = %v, want missing profile error", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package testchanged
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// openPTY is only implemented on Linux; other platforms fall back to pipes.
+func openPTY(cols, rows int) (master, tty *os.File, err error) {
+	return nil, nil, errors.New("pseudo-terminals are not supported on this platform")
+}
+
+func attachTTY(cmd *exec.Cmd, tty *os.File) {}
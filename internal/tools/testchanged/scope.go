@@ -0,0 +1,109 @@
+package testchanged
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// scopeKind selects which changes targets are discovered from.
+type scopeKind int
+
+const (
+	scopeMergeBase scopeKind = iota
+	scopeUncommitted
+	scopeStaged
+	scopeLastCommits
+	scopeRef
+)
+
+// scopeKinds lists the scopes in picker order.
+var scopeKinds = []scopeKind{scopeMergeBase, scopeUncommitted, scopeStaged, scopeLastCommits, scopeRef}
+
+// changeScope is the set of changes tests are selected for.
+type changeScope struct {
+	kind    scopeKind
+	commits int    // for scopeLastCommits
+	ref     string // for scopeRef
+}
+
+func (s changeScope) String() string {
+	switch s.kind {
+	case scopeUncommitted:
+		return "uncommitted changes"
+	case scopeStaged:
+		return "staged changes"
+	case scopeLastCommits:
+		if s.commits == 1 {
+			return "last commit"
+		}
+		return fmt.Sprintf("last %d commits", s.commits)
+	case scopeRef:
+		return "changes vs " + s.ref
+	default:
+		return "changes since merge base"
+	}
+}
+
+// files returns the files changed within the scope. base is the merge base
+// with the default branch, which only scopeMergeBase uses.
+func (s changeScope) files(base string) ([]string, error) {
+	switch s.kind {
+	case scopeUncommitted:
+		return diffNames("HEAD")
+	case scopeStaged:
+		return diffNames("--cached")
+	case scopeLastCommits:
+		return diffNames("HEAD~"+strconv.Itoa(s.commits), "HEAD")
+	case scopeRef:
+		return changedFiles(s.ref)
+	default:
+		return changedFiles(base)
+	}
+}
+
+// diffNames returns the names of files git diff reports for args.
+func diffNames(args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"diff", "--name-only"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	var files []string
+	for f := range strings.SplitSeq(string(out), "\n") {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+type refsLoadedMsg struct {
+	refs []string
+	err  error
+}
+
+// loadRefs lists local branches, remote-tracking branches and tags, most
+// recently committed first.
+func loadRefs() tea.Msg {
+	out, err := exec.Command("git", "for-each-ref", "--sort=-committerdate",
+		"--format=%(refname:short)%00%(symref)", "refs/heads", "refs/remotes", "refs/tags").Output()
+	if err != nil {
+		return refsLoadedMsg{err: fmt.Errorf("list refs: %w", err)}
+	}
+	var refs []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		// Skip symbolic refs such as <remote>/HEAD, which git shortens to
+		// the remote name alone.
+		name, symref, _ := strings.Cut(strings.TrimSpace(line), "\x00")
+		if name != "" && symref == "" {
+			refs = append(refs, name)
+		}
+	}
+	return refsLoadedMsg{refs: refs}
+}
@@ -0,0 +1,113 @@
+package testchanged
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestChangeScopeFiles(t *testing.T) {
+	gitRepo(t)
+
+	tests := []struct {
+		scope changeScope
+		want  []string
+	}{
+		{changeScope{kind: scopeUncommitted}, []string{"a.go", "c.go"}},
+		{changeScope{kind: scopeStaged}, []string{"c.go"}},
+		{changeScope{kind: scopeLastCommits, commits: 1}, []string{"b.go"}},
+		{changeScope{kind: scopeRef, ref: "HEAD~1"}, []string{"a.go", "b.go", "c.go"}},
+	}
+	for _, tt := range tests {
+		got, err := tt.scope.files("")
+		if err != nil {
+			t.Errorf("%s: %v", tt.scope, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: files = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+
+	if _, err := (changeScope{kind: scopeLastCommits, commits: 5}).files(""); err == nil {
+		t.Error("expected an error diffing past the first commit")
+	}
+}
+
+func TestScopePicker_SelectsLastCommits(t *testing.T) {
+	m := New()
+	m.state = stateBrowse
+
+	m = press(m, keyRune('c'))
+	if m.state != stateScope {
+		t.Fatalf("expected scope picker, got state %d", m.state)
+	}
+
+	m = press(m, keyRune('j'), keyRune('j'), keyRune('j'), keyCode(tea.KeyRight), keyCode(tea.KeyRight))
+	r, cmd := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.state != stateLoading || cmd == nil {
+		t.Fatalf("expected targets to reload, got state %d", m.state)
+	}
+	if want := (changeScope{kind: scopeLastCommits, commits: 3}); m.scope != want {
+		t.Errorf("scope = %+v, want %+v", m.scope, want)
+	}
+	if got := m.scope.String(); got != "last 3 commits" {
+		t.Errorf("scope label = %q", got)
+	}
+}
+
+func TestScopePicker_RefList(t *testing.T) {
+	m := New()
+	m.state = stateScope
+	m.scopeCursor = len(scopeKinds) - 1
+
+	r, _ := m.Update(refsLoadedMsg{refs: []string{"main", "origin/main", "v1.0.0"}})
+	m = r.(Model)
+	if m.state != stateScopeRef {
+		t.Fatalf("expected ref picker, got state %d", m.state)
+	}
+
+	m = press(m, keyRune('j'), keyRune('j'), keyCode(tea.KeyEnter))
+	if want := (changeScope{kind: scopeRef, ref: "v1.0.0"}); m.scope != want {
+		t.Errorf("scope = %+v, want %+v", m.scope, want)
+	}
+
+	m.state = stateScopeRef
+	m = press(m, keyCode(tea.KeyEscape))
+	if m.state != stateScope {
+		t.Errorf("esc should return to the scope picker, got state %d", m.state)
+	}
+}
+
+func TestLoadRefs_SkipsRemoteHEADs(t *testing.T) {
+	gitRepo(t)
+	git := gittest.Git(t)
+	git("update-ref", "refs/remotes/origin/main", "HEAD")
+	git("symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main")
+	git("update-ref", "refs/remotes/upstream/main", "HEAD")
+	git("symbolic-ref", "refs/remotes/upstream/HEAD", "refs/remotes/upstream/main")
+	git("tag", "v1")
+
+	msg := loadRefs().(refsLoadedMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	got := make(map[string]bool)
+	for _, r := range msg.refs {
+		got[r] = true
+	}
+	for _, want := range []string{"origin/main", "upstream/main", "v1"} {
+		if !got[want] {
+			t.Errorf("refs %v missing %s", msg.refs, want)
+		}
+	}
+	for _, skip := range []string{"origin", "upstream", "origin/HEAD", "upstream/HEAD"} {
+		if got[skip] {
+			t.Errorf("refs %v should not include %s", msg.refs, skip)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package testchanged
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/ryan-rushton/rig/internal/styles"
+)
+
+// Fixed output filters; values from filterFirstPkg onwards select the
+// package at index filter-filterFirstPkg.
+const (
+	filterAll = iota
+	filterFailures
+	filterPanics
+	filterFirstPkg
+)
+
+// setOutput replaces the results with lines and re-applies the current
+// filter and search.
+func (m *Model) setOutput(lines []string) {
+	m.output = lines
+	m.lineInfo = analyzeOutput(lines)
+	m.packages = nil
+	seen := make(map[string]bool)
+	for _, li := range m.lineInfo {
+		if li.pkg != "" && !seen[li.pkg] {
+			seen[li.pkg] = true
+			m.packages = append(m.packages, li.pkg)
+		}
+	}
+	if m.filter >= filterFirstPkg+len(m.packages) {
+		m.filter = filterAll
+	}
+	m.renderResults()
+}
+
+// filterLabel names the active filter for the status line.
+func (m Model) filterLabel() string {
+	switch {
+	case m.filter == filterFailures:
+		return "failures"
+	case m.filter == filterPanics:
+		return "panics"
+	case m.filter >= filterFirstPkg:
+		return m.packages[m.filter-filterFirstPkg]
+	default:
+		return ""
+	}
+}
+
+func (m Model) lineVisible(i int) bool {
+	li := m.lineInfo[i]
+	switch {
+	case m.filter == filterFailures:
+		return li.failure
+	case m.filter == filterPanics:
+		return li.panic
+	case m.filter >= filterFirstPkg:
+		return li.pkg == m.packages[m.filter-filterFirstPkg]
+	default:
+		return true
+	}
+}
+
+// renderResults rebuilds the results viewport from the filtered output,
+// highlighting search matches and recording match and failure positions
+// as viewport line numbers.
+func (m *Model) renderResults() {
+	m.visible, m.matches, m.failureStarts = nil, nil, nil
+	m.failureRow = -1
+
+	var rendered []string
+	prevFailure := false
+	for i, line := range m.output {
+		if !m.lineVisible(i) {
+			continue
+		}
+		row := len(m.visible)
+		m.visible = append(m.visible, i)
+
+		if m.lineInfo[i].failure && !prevFailure {
+			m.failureStarts = append(m.failureStarts, row)
+		}
+		prevFailure = m.lineInfo[i].failure
+
+		if spans := findMatches(ansi.Strip(line), m.query); len(spans) > 0 {
+			current := len(m.matches) == m.matchIdx
+			m.matches = append(m.matches, row)
+			rendered = append(rendered, highlightSpans(ansi.Strip(line), spans, current))
+			continue
+		}
+		rendered = append(rendered, colorizeLine(line))
+	}
+	if m.matchIdx >= len(m.matches) {
+		m.matchIdx = 0
+	}
+	m.resultsViewport.SetContent(strings.Join(rendered, "\n"))
+}
+
+// findMatches returns the byte ranges of query in line. The search is case
+// insensitive unless the query contains an upper-case letter.
+func findMatches(line, query string) [][2]int {
+	if query == "" {
+		return nil
+	}
+	haystack := line
+	if !strings.ContainsFunc(query, unicode.IsUpper) {
+		haystack = strings.ToLower(line)
+		query = strings.ToLower(query)
+		if len(haystack) != len(line) {
+			// Lower-casing changed byte offsets; fall back to exact matching.
+			haystack = line
+		}
+	}
+	var spans [][2]int
+	for off := 0; ; {
+		i := strings.Index(haystack[off:], query)
+		if i < 0 {
+			return spans
+		}
+		spans = append(spans, [2]int{off + i, off + i + len(query)})
+		off += i + len(query)
+	}
+}
+
+func highlightSpans(line string, spans [][2]int, current bool) string {
+	style := styles.Match
+	if current {
+		style = styles.CurrentMatch
+	}
+	var b strings.Builder
+	prev := 0
+	for _, s := range spans {
+		b.WriteString(line[prev:s[0]])
+		b.WriteString(style.Render(line[s[0]:s[1]]))
+		prev = s[1]
+	}
+	b.WriteString(line[prev:])
+	return b.String()
+}
+
+// scrollToRow centres row in the results viewport.
+func (m *Model) scrollToRow(row int) {
+	m.resultsViewport.SetYOffset(max(0, row-m.resultsViewport.Height()/2))
+}
+
+// jumpMatch moves to the next (dir=1) or previous (dir=-1) search match.
+func (m *Model) jumpMatch(dir int) {
+	if len(m.matches) == 0 {
+		return
+	}
+	m.matchIdx = (m.matchIdx + dir + len(m.matches)) % len(m.matches)
+	m.renderResults()
+	m.scrollToRow(m.matches[m.matchIdx])
+}
+
+// jumpFailure scrolls to the start of the next (dir=1) or previous (dir=-1)
+// block of failure output, relative to the last one jumped to.
+func (m *Model) jumpFailure(dir int) {
+	if dir > 0 {
+		for _, row := range m.failureStarts {
+			if row > m.failureRow {
+				m.failureRow = row
+				m.scrollToRow(row)
+				return
+			}
+		}
+		return
+	}
+	for i := len(m.failureStarts) - 1; i >= 0; i-- {
+		if row := m.failureStarts[i]; row < m.failureRow {
+			m.failureRow = row
+			m.scrollToRow(row)
+			return
+		}
+	}
+}
+
+// firstMatchFrom selects the first match at or below the top of the viewport.
+// The matches are recomputed first since the query or filter may have
+// changed; rendering again then highlights the chosen one.
+func (m *Model) firstMatchFrom(top int) {
+	m.matchIdx = 0
+	m.renderResults()
+	for i, row := range m.matches {
+		if row >= top {
+			m.matchIdx = i
+			break
+		}
+	}
+	if m.matchIdx > 0 {
+		m.renderResults()
+	}
+	if len(m.matches) > 0 {
+		m.scrollToRow(m.matches[m.matchIdx])
+	}
+}
+
+// resultsStatus summarises the active filter and search for the line under
+// the results viewport.
+func (m Model) resultsStatus() string {
+	var parts []string
+	if label := m.filterLabel(); label != "" {
+		parts = append(parts, "filter: "+label)
+	}
+	if m.query != "" {
+		if len(m.matches) == 0 {
+			parts = append(parts, fmt.Sprintf("/%s: no matches", m.query))
+		} else {
+			parts = append(parts, fmt.Sprintf("/%s: %d/%d", m.query, m.matchIdx+1, len(m.matches)))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,132 @@
+package testchanged
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// snapshot is a frozen copy of the working tree (HEAD plus uncommitted and
+// untracked changes) in a temporary worktree, so edits made while tests run
+// cannot affect them.
+type snapshot struct {
+	dir      string
+	prefix   string // cwd relative to the repo root
+	head     string
+	diffHash string // empty when the working tree was clean
+}
+
+// workDir is the snapshot equivalent of the directory rig was started in.
+func (s snapshot) workDir() string {
+	return filepath.Join(s.dir, s.prefix)
+}
+
+// label identifies the snapshot as base SHA plus a hash of the changes.
+func (s snapshot) label() string {
+	if s.diffHash == "" {
+		return shortSHA(s.head) + " (clean)"
+	}
+	return shortSHA(s.head) + "+" + s.diffHash
+}
+
+// createSnapshot copies the current working tree state into a new worktree.
+// Callers must removeWorktree(s.dir) when done.
+func createSnapshot() (snapshot, error) {
+	out, err := exec.Command("git", "rev-parse", "HEAD", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return snapshot{}, fmt.Errorf("resolve HEAD: %w", err)
+	}
+	// --show-prefix prints an empty line at the repo root, so pad to three.
+	parts := append(strings.Split(strings.TrimRight(string(out), "\n"), "\n"), "", "")
+	head, top, prefix := parts[0], parts[1], parts[2]
+
+	diff, err := exec.Command("git", "diff", "HEAD", "--binary").Output()
+	if err != nil {
+		return snapshot{}, fmt.Errorf("diff working tree: %w", err)
+	}
+
+	ls := exec.Command("git", "ls-files", "--others", "--exclude-standard", "-z")
+	ls.Dir = top
+	out, err = ls.Output()
+	if err != nil {
+		return snapshot{}, fmt.Errorf("list untracked files: %w", err)
+	}
+	var untracked []string
+	for f := range strings.SplitSeq(string(out), "\x00") {
+		if f != "" {
+			untracked = append(untracked, f)
+		}
+	}
+
+	dir, err := addWorktree(head)
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{dir: dir, prefix: prefix, head: head}
+
+	h := sha256.New()
+	h.Write(diff)
+
+	if len(diff) > 0 {
+		var buf bytes.Buffer
+		cmd := exec.Command("git", "apply", "--binary", "--whitespace=nowarn", "-")
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(diff)
+		cmd.Stderr = &buf
+		if err := cmd.Run(); err != nil {
+			removeWorktree(dir)
+			return snapshot{}, fmt.Errorf("apply changes to snapshot: %s", strings.TrimSpace(buf.String()))
+		}
+	}
+
+	for _, f := range untracked {
+		h.Write([]byte(f))
+		if err := copyInto(top, dir, f, h); err != nil {
+			removeWorktree(dir)
+			return snapshot{}, fmt.Errorf("copy %s to snapshot: %w", f, err)
+		}
+	}
+
+	if len(diff) > 0 || len(untracked) > 0 {
+		s.diffHash = hex.EncodeToString(h.Sum(nil))[:8]
+	}
+	return s, nil
+}
+
+// copyInto copies the repo-relative file rel from top into dir, preserving
+// its mode, and feeds its contents to h.
+func copyInto(top, dir, rel string, h io.Writer) error {
+	src, err := os.Open(filepath.Join(top, rel))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	dst := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(out, h), src); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,140 @@
+package testchanged
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestCreateSnapshot(t *testing.T) {
+	gitRepo(t) // a.go modified, b.go committed, c.go staged
+	if err := os.Remove("b.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gittest.WriteFile(t, filepath.Join("sub", "new.txt"), "untracked\n")
+
+	s, err := createSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeWorktree(s.dir)
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(s.dir, rel))
+		if err != nil {
+			t.Fatalf("read %s from snapshot: %v", rel, err)
+		}
+		return string(data)
+	}
+	if got := read("a.go"); got != "package a // edited\n" {
+		t.Errorf("modified a.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, "b.go")); !os.IsNotExist(err) {
+		t.Errorf("deleted b.go should be missing from the snapshot, got %v", err)
+	}
+	if got := read("c.go"); got != "package a\n" {
+		t.Errorf("staged c.go = %q", got)
+	}
+	if got := read("sub/new.txt"); got != "untracked\n" {
+		t.Errorf("untracked sub/new.txt = %q", got)
+	}
+	if s.prefix != "" || s.diffHash == "" || !strings.Contains(s.label(), "+"+s.diffHash) {
+		t.Errorf("snapshot = %+v, label %q", s, s.label())
+	}
+
+	// Edits after the snapshot do not reach it.
+	gittest.WriteFile(t, "a.go", "package a // later\n")
+	if got := read("a.go"); got != "package a // edited\n" {
+		t.Errorf("snapshot a.go changed to %q", got)
+	}
+
+	removeWorktree(s.dir)
+	if _, err := os.Stat(s.dir); !os.IsNotExist(err) {
+		t.Errorf("worktree dir should be removed, got %v", err)
+	}
+	out, err := exec.Command("git", "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "worktree "); n != 1 {
+		t.Errorf("expected only the main worktree, got:\n%s", out)
+	}
+}
+
+func TestCreateSnapshot_CleanTree(t *testing.T) {
+	gitRepo(t)
+	gittest.Git(t)("commit", "-qam", "c")
+	if err := os.MkdirAll("pkg", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir("pkg")
+
+	s, err := createSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeWorktree(s.dir)
+	if s.diffHash != "" || !strings.HasSuffix(s.label(), "(clean)") {
+		t.Errorf("clean snapshot labelled %q", s.label())
+	}
+	if s.prefix != "pkg/" || s.workDir() != filepath.Join(s.dir, "pkg") {
+		t.Errorf("prefix = %q, workDir = %q", s.prefix, s.workDir())
+	}
+}
+
+func TestCopyInto(t *testing.T) {
+	top, dir := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(top, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(top, "a", "b", "run.sh")
+	if err := os.WriteFile(src, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.New()
+	if err := copyInto(top, dir, "a/b/run.sh", h); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "a", "b", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	want := sha256.Sum256([]byte("#!/bin/sh\n"))
+	if got := hex.EncodeToString(h.Sum(nil)); got != hex.EncodeToString(want[:]) {
+		t.Error("contents should be fed to the hash")
+	}
+
+	if err := copyInto(top, dir, "missing", h); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestBrowse_SnapshotToggle(t *testing.T) {
+	m := New()
+	m.state = stateBrowse
+	m.runnerName = "go"
+	m.targets = []discoveredTarget{{runner: "go", target: "All"}, {runner: "go", target: "./a"}}
+
+	m = press(m, keyRune('s'))
+	if !m.opts.Snapshot || !strings.Contains(m.View().Content, "[snapshot]") {
+		t.Error("s should turn snapshots on and show it")
+	}
+	m = press(m, keyRune('s'))
+	if m.opts.Snapshot || strings.Contains(m.View().Content, "[snapshot]") {
+		t.Error("s again should turn snapshots off")
+	}
+}
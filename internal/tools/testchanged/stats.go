@@ -0,0 +1,145 @@
+package testchanged
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// targetStat is the outcome of the last run of a target.
+type targetStat struct {
+	Failed   bool          `json:"failed"`
+	Duration time.Duration `json:"duration"`
+	RunAt    time.Time     `json:"run_at"`
+}
+
+// targetStats maps targets to their last outcome. It is stored per
+// repository so ordering survives restarts.
+type targetStats map[string]targetStat
+
+// statsPath is the cache file for the repository containing the working
+// directory.
+func statsPath() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(string(out))))
+	return filepath.Join(cache, "rig", "test-changed", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadStats reads the stored stats. Missing or unreadable stats are not an
+// error: ordering just falls back to discovery order.
+func loadStats() targetStats {
+	stats := make(targetStats)
+	path, err := statsPath()
+	if err != nil {
+		return stats
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return stats
+	}
+	_ = json.Unmarshal(data, &stats)
+	return stats
+}
+
+// saveStats writes stats in the background. Failing to persist them only
+// loses ordering data, so errors are ignored.
+func saveStats(stats targetStats) tea.Cmd {
+	return func() tea.Msg {
+		path, err := statsPath()
+		if err != nil {
+			return nil
+		}
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		_ = os.WriteFile(path, data, 0o644)
+		return nil
+	}
+}
+
+// runStats returns the outcome of each target of a finished run. A single
+// target takes the outcome of the whole run; several are matched to the
+// package summaries in the output, and those without one are left out.
+func runStats(targets []string, msg testBatchMsg, now time.Time) targetStats {
+	stats := make(targetStats)
+	if len(targets) == 1 {
+		stats[targets[0]] = targetStat{Failed: msg.err != nil, Duration: msg.elapsed, RunAt: now}
+		return stats
+	}
+	module := modulePath()
+	for _, line := range msg.lines {
+		r, ok := parsePackageResult(line)
+		if !ok {
+			continue
+		}
+		t, ok := targetFor(targets, r.pkg, module)
+		if !ok {
+			continue
+		}
+		s := stats[t]
+		s.Failed = s.Failed || !r.passed
+		s.Duration += r.duration
+		s.RunAt = now
+		stats[t] = s
+	}
+	return stats
+}
+
+// targetFor returns the target a package summary belongs to: the target
+// itself for Bazel labels, otherwise the most specific go package pattern
+// covering the package's directory.
+func targetFor(targets []string, pkg, module string) (string, bool) {
+	if slices.Contains(targets, pkg) {
+		return pkg, true
+	}
+	dir := packageDir(pkg, module)
+	best, found := "", false
+	for _, t := range targets {
+		tdir, ok := strings.CutSuffix(strings.TrimPrefix(t, "./"), "/...")
+		if t == "./..." {
+			tdir, ok = ".", true
+		}
+		if !ok {
+			continue
+		}
+		if (tdir == "." || dir == tdir || strings.HasPrefix(dir, tdir+"/")) && len(t) > len(best) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// orderTargets returns targets with those that failed last time first, then
+// slowest first. Targets without stats count as fast. Ties keep discovery
+// order.
+func orderTargets(targets []string, stats targetStats) []string {
+	ordered := append([]string(nil), targets...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		si, sj := stats[ordered[i]], stats[ordered[j]]
+		if si.Failed != sj.Failed {
+			return si.Failed
+		}
+		return si.Duration > sj.Duration
+	})
+	return ordered
+}
@@ -0,0 +1,176 @@
+package testchanged
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/ryan-rushton/rig/internal/gittest"
+)
+
+func TestOrderTargets(t *testing.T) {
+	stats := targetStats{
+		"./fast/...":   {Duration: time.Second},
+		"./slow/...":   {Duration: time.Minute},
+		"./broken/...": {Failed: true, Duration: time.Second},
+		"./flaky/...":  {Failed: true, Duration: 10 * time.Second},
+	}
+	targets := []string{"./fast/...", "./new/...", "./broken/...", "./slow/...", "./flaky/...", "./other/..."}
+
+	got := orderTargets(targets, stats)
+	want := []string{"./flaky/...", "./broken/...", "./slow/...", "./fast/...", "./new/...", "./other/..."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderTargets = %v, want %v", got, want)
+	}
+	if targets[0] != "./fast/..." {
+		t.Error("orderTargets modified its input")
+	}
+}
+
+func modelWithTargets(targets ...string) Model {
+	m := New()
+	r, _ := m.Update(targetsLoadedMsg{runner: "go", targets: targets, stats: targetStats{}})
+	return r.(Model)
+}
+
+func TestSequence_OrdersAndFailsFast(t *testing.T) {
+	m := modelWithTargets("./a/...", "./b/...", "./c/...")
+	m.stats = targetStats{"./c/...": {Failed: true}, "./a/...": {Duration: time.Second}, "./b/...": {Duration: time.Minute}}
+	m = press(m, keyRune('o'), keyRune('f'))
+
+	r, _ := m.Update(keyCode(tea.KeyEnter))
+	m = r.(Model)
+	if m.seq == nil || m.seq.current != "./c/..." {
+		t.Fatalf("expected previously failed target to run first, got %+v", m.seq)
+	}
+
+	r, _ = m.Update(testBatchMsg{lines: []string{"FAIL    example.com/c 0.1s"}, elapsed: 2 * time.Second, err: errors.New("exit status 1")})
+	m = r.(Model)
+	if m.state != stateResults || m.exitCode != 1 || m.seq != nil {
+		t.Fatalf("expected fail-fast results, got state %d exit %d", m.state, m.exitCode)
+	}
+	out := strings.Join(m.output, "\n")
+	if !strings.Contains(out, "fail-fast: skipped 2 remaining target(s): ./b/... ./a/...") {
+		t.Errorf("output missing skipped targets:\n%s", out)
+	}
+	if s := m.stats["./c/..."]; !s.Failed || s.Duration != 2*time.Second {
+		t.Errorf("stats not updated: %+v", s)
+	}
+}
+
+func TestSequence_RunsAllTargets(t *testing.T) {
+	m := modelWithTargets("./a/...", "./b/...")
+	m = press(m, keyRune('f'))
+
+	m = press(m, keyCode(tea.KeyEnter))
+	r, _ := m.Update(testBatchMsg{lines: []string{"ok      example.com/a 0.1s"}})
+	m = r.(Model)
+	if m.state != stateRunning || m.seq == nil || m.seq.current != "./b/..." {
+		t.Fatalf("expected second target to start, got state %d seq %+v", m.state, m.seq)
+	}
+	r, _ = m.Update(testBatchMsg{lines: []string{"ok      example.com/b 0.1s"}})
+	m = r.(Model)
+	if m.state != stateResults || m.exitCode != 0 {
+		t.Fatalf("expected passing results, got state %d exit %d", m.state, m.exitCode)
+	}
+	if !reflect.DeepEqual(m.packages, []string{"example.com/a", "example.com/b"}) {
+		t.Errorf("packages = %v, want output of both targets", m.packages)
+	}
+}
+
+func TestSequence_SharesSnapshot(t *testing.T) {
+	gitRepo(t)
+	m := modelWithTargets("./a/...", "./b/...")
+	m = press(m, keyRune('f'), keyRune('s'))
+
+	m = press(m, keyCode(tea.KeyEnter))
+	if m.seq == nil || m.seq.snap != nil || m.loadingMsg != "Snapshotting working tree..." {
+		t.Fatalf("expected the sequence to snapshot first, got %q", m.loadingMsg)
+	}
+	snap, err := createSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := m.Update(sequenceSnapshotMsg{snap: snap})
+	m = r.(Model)
+	first := m.seq.snap
+	if first == nil || first.dir != snap.dir {
+		t.Fatalf("sequence snapshot = %+v", first)
+	}
+
+	r, _ = m.Update(testBatchMsg{lines: []string{"ok      example.com/a 0.1s"}})
+	m = r.(Model)
+	if m.seq == nil || m.seq.snap != first {
+		t.Fatal("the next target should reuse the snapshot")
+	}
+	if _, err := os.Stat(snap.dir); err != nil {
+		t.Fatalf("snapshot removed mid-sequence: %v", err)
+	}
+
+	cleanup := m.endSequence()
+	if m.seq != nil || cleanup == nil {
+		t.Fatal("ending the sequence should remove its snapshot")
+	}
+	cleanup()
+	if _, err := os.Stat(snap.dir); !os.IsNotExist(err) {
+		t.Errorf("snapshot should be removed, got %v", err)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gittest.WriteFile(t, "go.mod", "module example.com/m\n")
+	now := time.Now()
+
+	one := runStats([]string{"./a/..."}, testBatchMsg{elapsed: time.Second, err: errors.New("exit status 1")}, now)
+	if want := (targetStats{"./a/...": {Failed: true, Duration: time.Second, RunAt: now}}); !reflect.DeepEqual(one, want) {
+		t.Errorf("single target stats = %+v, want %+v", one, want)
+	}
+
+	lines := []string{
+		"ok      example.com/m 0.5s",
+		"ok      example.com/m/a 1s",
+		"FAIL    example.com/m/a/b 2s",
+		"?       example.com/m/c [no test files]",
+	}
+	got := runStats([]string{"./...", "./a/...", "./c/...", "./d/..."}, testBatchMsg{lines: lines}, now)
+	want := targetStats{
+		"./...":   {Duration: 500 * time.Millisecond, RunAt: now},
+		"./a/...": {Failed: true, Duration: 3 * time.Second, RunAt: now},
+		"./c/...": {RunAt: now},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runStats = %+v, want %+v", got, want)
+	}
+
+	bazel := runStats([]string{"//a:test", "//b:test"}, testBatchMsg{lines: []string{
+		"//a:test   PASSED in 0.4s",
+		"//b:test   FAILED in 1.5s",
+	}}, now)
+	if !bazel["//b:test"].Failed || bazel["//a:test"].Failed || bazel["//a:test"].Duration != 400*time.Millisecond {
+		t.Errorf("bazel stats = %+v", bazel)
+	}
+}
+
+func TestRun_RecordsStats(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gittest.WriteFile(t, "go.mod", "module example.com\n")
+	m := modelWithTargets("./a/...", "./b/...")
+	m.stats = targetStats{"./b/...": {Failed: true}}
+	prev := m.stats
+	m = press(m, keyCode(tea.KeyEnter)) // All, without a sequence
+
+	r, _ := m.Update(testBatchMsg{lines: []string{"ok      example.com/a 0.1s", "ok      example.com/b 0.2s"}})
+	m = r.(Model)
+	if m.stats["./b/..."].Failed || m.stats["./b/..."].Duration != 200*time.Millisecond {
+		t.Errorf("stats not updated: %+v", m.stats)
+	}
+	if !prev["./b/..."].Failed {
+		t.Error("recording stats modified a map shared with older models")
+	}
+}
@@ -0,0 +1,176 @@
+package testchanged
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// outputMsg carries the output of a run that is still in progress, as the
+// change since the previous outputMsg of the run.
+type outputMsg struct {
+	run     *testRun
+	dropped int // lines removed from the top
+	from    int // index lines replace the output from
+	lines   []string
+}
+
+// apply returns output updated with the change.
+func (msg outputMsg) apply(output []string) []string {
+	output = output[min(msg.dropped, len(output)):]
+	return append(slices.Clip(output[:min(msg.from, len(output))]), msg.lines...)
+}
+
+// testRun is a test process whose output is streamed to the model. Output
+// updates are coalesced so a slow UI only ever sees the latest state.
+type testRun struct {
+	mu      sync.Mutex
+	term    termBuffer    // guarded by mu
+	updates chan struct{} // signalled when term has changed
+	done    chan testBatchMsg
+	// finish, if set, converts the final result into a different message.
+	finish func(testBatchMsg) tea.Msg
+}
+
+// wait returns a command that delivers the next update, or the final
+// testBatchMsg once the process exits.
+func (r *testRun) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-r.updates:
+			r.mu.Lock()
+			dropped, from, lines := r.term.changes()
+			r.mu.Unlock()
+			return outputMsg{run: r, dropped: dropped, from: from, lines: lines}
+		case msg := <-r.done:
+			if r.finish != nil {
+				return r.finish(msg)
+			}
+			return msg
+		}
+	}
+}
+
+func (r *testRun) write(p []byte) {
+	r.mu.Lock()
+	_, _ = r.term.Write(p)
+	r.mu.Unlock()
+	select {
+	case r.updates <- struct{}{}:
+	default:
+	}
+}
+
+// startRun starts cmd under a pseudo-terminal of the given size so runners
+// keep their colors and progress output, falling back to a pipe shared by
+// stdout and stderr where that isn't available. Only the last maxLines lines
+// of output are kept. snap is the snapshot cmd runs in, if any. cleanup runs
+// after exit.
+func startRun(cmd *exec.Cmd, cols, rows, maxLines int, snap *snapshot, cleanup func()) (*testRun, error) {
+	start := time.Now()
+	var out *os.File
+	if master, tty, err := openPTY(cols, rows); err == nil {
+		attachTTY(cmd, tty)
+		if err := cmd.Start(); err != nil {
+			_ = master.Close()
+			_ = tty.Close()
+			return nil, err
+		}
+		_ = tty.Close()
+		out = master
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout, cmd.Stderr = w, w
+		if err := cmd.Start(); err != nil {
+			_ = r.Close()
+			_ = w.Close()
+			return nil, err
+		}
+		_ = w.Close()
+		out = r
+	}
+
+	run := &testRun{
+		term:    termBuffer{maxLines: maxLines},
+		updates: make(chan struct{}, 1),
+		done:    make(chan testBatchMsg, 1),
+	}
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := out.Read(buf)
+			if n > 0 {
+				run.write(buf[:n])
+			}
+			// EOF from a pipe, or EIO from a PTY once the child has exited.
+			if err != nil {
+				break
+			}
+		}
+		_ = out.Close()
+		exitErr := cmd.Wait()
+		if cleanup != nil {
+			cleanup()
+		}
+		run.mu.Lock()
+		lines := run.term.Lines()
+		run.mu.Unlock()
+		msg := testBatchMsg{lines: lines, elapsed: time.Since(start), err: exitErr}
+		if snap != nil {
+			msg.snapshot, msg.snapshotDir = snap.label(), snap.workDir()
+		}
+		run.done <- msg
+	}()
+	return run, nil
+}
+
+// streamLines returns a tea.Cmd that starts the tests and then streams their
+// output, allowing the TUI to render progressively. The tests run in snap
+// when given, which the caller removes. Otherwise when isolate is set they
+// run in a new snapshot of the working tree, removed once they finish.
+func streamLines(runner string, targets []string, snap *snapshot, isolate bool, cols, rows, maxLines int) tea.Cmd {
+	return func() tea.Msg {
+		var r TestRunner
+		for _, candidate := range allRunners() {
+			if candidate.Name() == runner {
+				r = candidate
+				break
+			}
+		}
+		if r == nil {
+			return testDoneMsg{err: fmt.Errorf("runner %q not found", runner)}
+		}
+
+		cmd := r.RunTests(targets)
+
+		var cleanup func()
+		if snap == nil && isolate {
+			s, err := createSnapshot()
+			if err != nil {
+				return testDoneMsg{err: err}
+			}
+			snap = &s
+			cleanup = func() { removeWorktree(s.dir) }
+		}
+		if snap != nil {
+			cmd.Dir = snap.workDir()
+		}
+
+		run, err := startRun(cmd, cols, rows, maxLines, snap, cleanup)
+		if err != nil {
+			if cleanup != nil {
+				cleanup()
+			}
+			return testDoneMsg{err: err}
+		}
+		return run.wait()()
+	}
+}
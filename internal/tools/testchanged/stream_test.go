@@ -0,0 +1,36 @@
+package testchanged
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestStartRun_KeepsLastLines(t *testing.T) {
+	run, err := startRun(exec.Command("sh", "-c", "for i in $(seq 1 50); do echo line $i; done"), 80, 24, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output []string
+	for {
+		switch msg := run.wait()().(type) {
+		case outputMsg:
+			output = msg.apply(output)
+		case testBatchMsg:
+			var want []string
+			for i := 42; i <= 50; i++ {
+				want = append(want, fmt.Sprintf("line %d", i))
+			}
+			if msg.err != nil || !reflect.DeepEqual(msg.lines, want) {
+				t.Fatalf("final lines = %q (%v), want %q", msg.lines, msg.err, want)
+			}
+			if len(output) > 10 {
+				t.Errorf("streamed %d lines, want at most 10", len(output))
+			}
+			return
+		default:
+			t.Fatalf("unexpected %T", msg)
+		}
+	}
+}
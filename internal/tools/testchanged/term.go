@@ -0,0 +1,372 @@
+package testchanged
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxPending bounds an unterminated escape sequence carried between writes.
+const maxPending = 4096
+
+// cell is one character of terminal output with the SGR style active when
+// it was written.
+type cell struct {
+	r     rune
+	style string
+}
+
+// termBuffer is a minimal line-oriented terminal emulator. It interprets the
+// control sequences test runners use for colors and progress (SGR, carriage
+// return, cursor movement, erase) and drops everything else, so the output
+// can be rendered safely in a viewport. Rendered lines only ever contain SGR
+// sequences and always end with their styles reset.
+//
+// When maxLines is set the oldest rows are dropped to stay within it.
+// changes reports what moved since it was last called, so a consumer can
+// keep its own copy up to date without copying every line on each update.
+type termBuffer struct {
+	maxLines int
+	lines    [][]cell
+	rendered []string
+	dirty    []bool
+	row, col int
+	sgr      sgrState
+	style    string // sgr rendered, cached for new cells
+	pending  []byte
+
+	// Since changes was last called: rows dropped from the top, the first
+	// row that changed, and how many lines it returned then.
+	trimmed     int
+	changedFrom int
+	published   int
+}
+
+// Write feeds raw terminal output into the buffer. Escape sequences and
+// UTF-8 runes split across writes are carried over to the next call.
+func (t *termBuffer) Write(p []byte) (int, error) {
+	data := p
+	if len(t.pending) > 0 {
+		data = append(t.pending, p...)
+		t.pending = nil
+	}
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == 0x1b:
+			n := t.escape(data[i:])
+			if n == 0 {
+				if len(data)-i <= maxPending {
+					t.pending = append([]byte(nil), data[i:]...)
+				}
+				return len(p), nil
+			}
+			i += n
+			continue
+		case b == '\r':
+			t.col = 0
+		case b == '\n':
+			t.row++
+			t.col = 0
+			t.ensureRow()
+		case b == '\b':
+			t.col = max(0, t.col-1)
+		case b == '\t':
+			t.col = (t.col/8 + 1) * 8
+		case b < 0x20 || b == 0x7f:
+			// Other control characters (bell, shift in/out, ...) are dropped.
+		default:
+			if !utf8.FullRune(data[i:]) {
+				t.pending = append([]byte(nil), data[i:]...)
+				return len(p), nil
+			}
+			r, size := utf8.DecodeRune(data[i:])
+			t.put(r)
+			i += size
+			continue
+		}
+		i++
+	}
+	return len(p), nil
+}
+
+// escape handles the escape sequence at the start of data and returns its
+// length, or 0 if the sequence is incomplete.
+func (t *termBuffer) escape(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+	switch data[1] {
+	case '[':
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				t.csi(string(data[2:i]), data[i])
+				return i + 1
+			}
+		}
+		return 0
+	case ']':
+		// OSC (titles, hyperlinks, notifications) ends with BEL or ST.
+		for i := 2; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return 0
+	case '(', ')', '*', '+':
+		// Character set designation takes one more byte.
+		if len(data) < 3 {
+			return 0
+		}
+		return 3
+	default:
+		return 2
+	}
+}
+
+// csi applies a control sequence with the given parameters and final byte.
+func (t *termBuffer) csi(params string, final byte) {
+	if strings.ContainsAny(params, "?<=>") {
+		// Private modes (cursor visibility, bracketed paste, ...) don't
+		// affect the text.
+		return
+	}
+	arg := func(def int) int {
+		first, _, _ := strings.Cut(params, ";")
+		n, err := strconv.Atoi(first)
+		if err != nil || n == 0 {
+			return def
+		}
+		return n
+	}
+
+	switch final {
+	case 'm':
+		t.sgr.apply(params)
+		t.style = t.sgr.String()
+	case 'A':
+		t.row = max(0, t.row-arg(1))
+	case 'B':
+		t.row += arg(1)
+		t.ensureRow()
+	case 'C':
+		t.col += arg(1)
+	case 'D':
+		t.col = max(0, t.col-arg(1))
+	case 'G':
+		t.col = arg(1) - 1
+	case 'K':
+		t.eraseLine(arg(0))
+	case 'J':
+		if arg(0) == 0 {
+			t.eraseLine(0)
+			if t.row+1 < len(t.lines) {
+				t.lines = t.lines[:t.row+1]
+				t.rendered = t.rendered[:t.row+1]
+				t.dirty = t.dirty[:t.row+1]
+			}
+		}
+	}
+}
+
+// sgrState is the graphic rendition in effect: which attributes are on and
+// the current colors. Keeping it as state rather than the sequences seen
+// means a long run of style changes renders as one short sequence.
+type sgrState struct {
+	attrs  uint16 // bit n set when attribute n (1 bold ... 9 strikethrough) is on
+	fg, bg string // parameters selecting the color, e.g. "31" or "38;5;208"
+}
+
+// apply updates the state with the parameters of an SGR sequence.
+func (s *sgrState) apply(params string) {
+	ps := strings.Split(params, ";")
+	for i := 0; i < len(ps); i++ {
+		n, err := strconv.Atoi(ps[i])
+		if ps[i] == "" {
+			n, err = 0, nil
+		}
+		if err != nil {
+			continue // sub-parameters and the like are not tracked
+		}
+		switch {
+		case n == 0:
+			*s = sgrState{}
+		case n >= 1 && n <= 9:
+			s.attrs |= 1 << n
+		case n == 22:
+			s.attrs &^= 1<<1 | 1<<2
+		case n >= 23 && n <= 29:
+			s.attrs &^= 1 << (n - 20)
+		case n >= 30 && n <= 37, n >= 90 && n <= 97:
+			s.fg = ps[i]
+		case n == 39:
+			s.fg = ""
+		case n >= 40 && n <= 47, n >= 100 && n <= 107:
+			s.bg = ps[i]
+		case n == 49:
+			s.bg = ""
+		case n == 38 || n == 48:
+			// Extended colors: 5;index or 2;r;g;b.
+			end := i + 1
+			if end < len(ps) && ps[end] == "5" {
+				end += 2
+			} else if end < len(ps) && ps[end] == "2" {
+				end += 4
+			}
+			if end > len(ps) {
+				return
+			}
+			color := strings.Join(ps[i:end], ";")
+			if n == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+			i = end - 1
+		}
+	}
+}
+
+// String renders the state as a single SGR sequence, or "" when it is the
+// default.
+func (s sgrState) String() string {
+	var ps []string
+	for n := 1; n <= 9; n++ {
+		if s.attrs&(1<<n) != 0 {
+			ps = append(ps, strconv.Itoa(n))
+		}
+	}
+	if s.fg != "" {
+		ps = append(ps, s.fg)
+	}
+	if s.bg != "" {
+		ps = append(ps, s.bg)
+	}
+	if len(ps) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(ps, ";") + "m"
+}
+
+// eraseLine implements CSI K: 0 erases to the end of the line, 1 to the
+// cursor, and 2 the whole line.
+func (t *termBuffer) eraseLine(mode int) {
+	t.ensureRow()
+	line := t.lines[t.row]
+	switch mode {
+	case 0:
+		if t.col < len(line) {
+			line = line[:t.col]
+		}
+	case 1:
+		for i := 0; i <= t.col && i < len(line); i++ {
+			line[i] = cell{r: ' '}
+		}
+	case 2:
+		line = line[:0]
+	}
+	t.lines[t.row] = line
+	t.touch(t.row)
+}
+
+// touch marks row as needing to be rendered and reported.
+func (t *termBuffer) touch(row int) {
+	t.dirty[row] = true
+	t.changedFrom = min(t.changedFrom, row)
+}
+
+func (t *termBuffer) ensureRow() {
+	if len(t.lines) <= t.row {
+		t.changedFrom = min(t.changedFrom, len(t.lines))
+	}
+	for len(t.lines) <= t.row {
+		t.lines = append(t.lines, nil)
+		t.rendered = append(t.rendered, "")
+		t.dirty = append(t.dirty, false)
+	}
+	if t.maxLines > 0 && len(t.lines) > t.maxLines {
+		k := len(t.lines) - t.maxLines
+		t.lines = t.lines[k:]
+		t.rendered = t.rendered[k:]
+		t.dirty = t.dirty[k:]
+		t.row = max(0, t.row-k)
+		t.trimmed += k
+		t.changedFrom = max(0, t.changedFrom-k)
+		t.published = max(0, t.published-k)
+	}
+}
+
+func (t *termBuffer) put(r rune) {
+	t.ensureRow()
+	line := t.lines[t.row]
+	for len(line) < t.col {
+		line = append(line, cell{r: ' '})
+	}
+	c := cell{r: r, style: t.style}
+	if t.col < len(line) {
+		line[t.col] = c
+	} else {
+		line = append(line, c)
+	}
+	t.lines[t.row] = line
+	t.touch(t.row)
+	t.col++
+}
+
+// Lines returns the rendered output, omitting a trailing empty line left
+// by a final newline.
+func (t *termBuffer) Lines() []string {
+	n := t.render()
+	return append([]string(nil), t.rendered[:n]...)
+}
+
+// changes returns the output that changed since the last call: dropped
+// lines were removed from the top, and lines replace everything from
+// index from onwards.
+func (t *termBuffer) changes() (dropped, from int, lines []string) {
+	n := t.render()
+	from = min(t.changedFrom, t.published, n)
+	lines = append([]string(nil), t.rendered[from:n]...)
+	dropped = t.trimmed
+	t.trimmed, t.changedFrom, t.published = 0, len(t.lines), n
+	return dropped, from, lines
+}
+
+// render brings the rendered lines up to date and returns how many there
+// are, leaving out a trailing empty line.
+func (t *termBuffer) render() int {
+	for i, d := range t.dirty {
+		if d {
+			t.rendered[i] = renderCells(t.lines[i])
+			t.dirty[i] = false
+		}
+	}
+	n := len(t.rendered)
+	if n > 0 && t.rendered[n-1] == "" {
+		n--
+	}
+	return n
+}
+
+func renderCells(cells []cell) string {
+	var b strings.Builder
+	style := ""
+	for _, c := range cells {
+		if c.style != style {
+			if style != "" {
+				b.WriteString("\x1b[0m")
+			}
+			b.WriteString(c.style)
+			style = c.style
+		}
+		b.WriteRune(c.r)
+	}
+	if style != "" {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
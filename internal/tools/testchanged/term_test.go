@@ -0,0 +1,133 @@
+package testchanged
+
+import (
+	"reflect"
+	"testing"
+)
+
+func termLines(writes ...string) []string {
+	var t termBuffer
+	for _, w := range writes {
+		_, _ = t.Write([]byte(w))
+	}
+	return t.Lines()
+}
+
+func TestTermBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "plain lines",
+			writes: []string{"ok  pkg\r\nFAIL other\r\n"},
+			want:   []string{"ok  pkg", "FAIL other"},
+		},
+		{
+			name:   "carriage return overwrites progress",
+			writes: []string{"[1/3] building\r[2/3] testing\r[3/3] done\x1b[K\r\nPASS\n"},
+			want:   []string{"[3/3] done", "PASS"},
+		},
+		{
+			name:   "sgr kept and reset at line end",
+			writes: []string{"\x1b[32mPASS\x1b[0m tail\n\x1b[1;31mFAIL\n"},
+			want:   []string{"\x1b[32mPASS\x1b[0m tail", "\x1b[1;31mFAIL\x1b[0m"},
+		},
+		{
+			name:   "cursor up and erase redraws previous line",
+			writes: []string{"status: 1 running\nlast\n\x1b[2A\x1b[Kstatus: done\n"},
+			want:   []string{"status: done", "last"},
+		},
+		{
+			name:   "escape split across writes",
+			writes: []string{"a\x1b[3", "1mb\x1b", "[0mc\n"},
+			want:   []string{"a\x1b[31mb\x1b[0mc"},
+		},
+		{
+			name:   "osc and private modes dropped",
+			writes: []string{"\x1b]0;title\x07\x1b[?25lhello\x1b]8;;http://x\x1b\\link\n"},
+			want:   []string{"hellolink"},
+		},
+		{
+			name:   "tabs expand to spaces",
+			writes: []string{"ok\tpkg\n"},
+			want:   []string{"ok      pkg"},
+		},
+		{
+			name:   "sgr changes replace the style",
+			writes: []string{"\x1b[1;31mA\x1b[39mB\x1b[22mC\x1b[38;5;208;4mD\x1b[24;49mE\n"},
+			want:   []string{"\x1b[1;31mA\x1b[0m\x1b[1mB\x1b[0mC\x1b[4;38;5;208mD\x1b[0m\x1b[38;5;208mE\x1b[0m"},
+		},
+		{
+			name:   "utf-8 split across writes",
+			writes: []string{"\xe2\x9c", "\x93 ok\n"},
+			want:   []string{"✓ ok"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := termLines(tt.writes...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermBuffer_StyleStaysBounded(t *testing.T) {
+	var term termBuffer
+	for range 1000 {
+		_, _ = term.Write([]byte("\x1b[1m\x1b[32m\x1b[22m\x1b[39m"))
+	}
+	_, _ = term.Write([]byte("\x1b[2mx\n"))
+	if got := term.Lines(); !reflect.DeepEqual(got, []string{"\x1b[2mx\x1b[0m"}) {
+		t.Errorf("lines = %q", got)
+	}
+	if term.style != "\x1b[2m" {
+		t.Errorf("style = %q", term.style)
+	}
+}
+
+func TestTermBuffer_Changes(t *testing.T) {
+	term := termBuffer{maxLines: 4}
+	var output []string
+	writes := []string{
+		"one\n",
+		"two\nthr",
+		"ee\n",
+		"four\nfive\nsix\n", // drops one and two
+		"\x1b[2Aredrawn\x1b[K\n\n",
+		"[1/2]\r[2/2]",
+		"\x1b[2A\x1b[J",
+		"seven\neight\nnine\nten\neleven\n",
+	}
+	for _, w := range writes {
+		_, _ = term.Write([]byte(w))
+		dropped, from, lines := term.changes()
+		output = outputMsg{dropped: dropped, from: from, lines: lines}.apply(output)
+		if want := term.Lines(); !reflect.DeepEqual(output, want) {
+			t.Fatalf("after %q: applied changes give %q, want %q", w, output, want)
+		}
+		if len(output) > term.maxLines {
+			t.Fatalf("after %q: %d lines kept, want at most %d", w, len(output), term.maxLines)
+		}
+	}
+	// The cursor's empty row after the final newline counts towards the cap.
+	if want := []string{"nine", "ten", "eleven"}; !reflect.DeepEqual(output, want) {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	_, from, lines := term.changes()
+	if from != 3 || len(lines) != 0 {
+		t.Errorf("no writes should report no changes, got %d %q", from, lines)
+	}
+}
+
+func TestOutputMsg_ApplyKeepsOlderOutput(t *testing.T) {
+	old := []string{"a", "b", "c"}
+	got := outputMsg{from: 2, lines: []string{"C", "d"}}.apply(old)
+	if !reflect.DeepEqual(got, []string{"a", "b", "C", "d"}) || old[2] != "c" {
+		t.Errorf("got %q, old %q", got, old)
+	}
+}